@@ -0,0 +1,238 @@
+package booking
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+ * Bank statements are read from the CSVs and OFX files banks export. A CSV
+ * has a header row naming its columns, in any order and case:
+ *
+ *   date         "2017-07-03" or "03/07/2017"
+ *   description  what the bank says the payment is, also "memo" or "reference"
+ *   amount       positive for money in, also "credit" with "debit" beside it
+ *   id           the bank's id for the transaction, if it gives one
+ *
+ * OFX files, in the SGML of version 1 or the XML of version 2, give each
+ * transaction as a STMTTRN with a DTPOSTED, TRNAMT, FITID, NAME and MEMO.
+ */
+
+// Transaction is a line of a bank statement
+type Transaction struct {
+	// ID is the bank's id for the transaction, or one made from its date,
+	// amount and description if the bank gives none
+	ID          string
+	Date        time.Time
+	Amount      float64
+	Description string
+}
+
+// ReadStatement reads a bank statement file, as OFX if it ends .ofx or .qfx
+// and as CSV otherwise
+func ReadStatement(file string) ([]Transaction, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var transactions []Transaction
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".ofx", ".qfx":
+		transactions, err = ReadOFX(bytes.NewReader(data))
+	default:
+		transactions, err = ReadStatementCSV(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return transactions, nil
+}
+
+// statementColumns are the names a statement CSV's columns go by
+var statementColumns = map[string][]string{
+	"date":        {"date", "transaction date", "posted"},
+	"description": {"description", "memo", "reference", "details"},
+	"amount":      {"amount", "credit"},
+	"debit":       {"debit"},
+	"id":          {"id", "transaction id", "fitid"},
+}
+
+// ReadStatementCSV reads the transactions in a bank statement CSV
+func ReadStatementCSV(in io.Reader) ([]Transaction, error) {
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no header row")
+	}
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for column, names := range statementColumns {
+			if _, ok := columns[column]; !ok && containsString(names, name) {
+				columns[column] = i
+			}
+		}
+	}
+	for _, column := range []string{"date", "description", "amount"} {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("no %s column", column)
+		}
+	}
+	field := func(row []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+	var transactions []Transaction
+	seen := make(map[string]int)
+	for i, row := range rows[1:] {
+		if len(row) == 1 && strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		t := Transaction{ID: field(row, "id"), Description: field(row, "description")}
+		if t.Date, err = parseStatementDate(field(row, "date")); err != nil {
+			return nil, fmt.Errorf("row %d: %v", i+2, err)
+		}
+		if t.Amount, err = parseStatementAmount(field(row, "amount")); err != nil {
+			return nil, fmt.Errorf("row %d: %v", i+2, err)
+		}
+		if debit := field(row, "debit"); debit != "" {
+			amount, err := parseStatementAmount(debit)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %v", i+2, err)
+			}
+			t.Amount -= amount
+		}
+		transactions = append(transactions, t.withID(seen))
+	}
+	return transactions, nil
+}
+
+// statementDateLayouts are the ways statement CSVs write dates
+var statementDateLayouts = []string{DateLayout, "02/01/2006", "2/1/2006", "02 Jan 2006", "2 Jan 2006"}
+
+func parseStatementDate(value string) (time.Time, error) {
+	for _, layout := range statementDateLayouts {
+		if date, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("date %q is not like 2017-07-03 or 03/07/2017", value)
+}
+
+// parseStatementAmount reads an amount, with or without a currency symbol and
+// thousands separators, and "" as 0
+func parseStatementAmount(value string) (float64, error) {
+	value = strings.NewReplacer(",", "", "£", "", "€", "", "$", "", " ", "").Replace(value)
+	if value == "" {
+		return 0, nil
+	}
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("amount %q is not a number", value)
+	}
+	return pence(amount), nil
+}
+
+// withID gives a transaction without an id one made from its date, amount and
+// description, so the same line of a statement always has the same id. seen
+// counts the ids made so far, so that identical lines, e.g. two equal payouts
+// on the same day, are told apart by where they come in the statement.
+func (t Transaction) withID(seen map[string]int) Transaction {
+	if t.ID != "" {
+		return t
+	}
+	key := fmt.Sprintf("%s %.2f %s", t.Date.Format(DateLayout), t.Amount, t.Description)
+	t.ID = key
+	if n := seen[key]; n > 0 {
+		t.ID = fmt.Sprintf("%s #%d", key, n+1)
+	}
+	seen[key]++
+	return t
+}
+
+// ReadOFX reads the transactions in an OFX file
+func ReadOFX(in io.Reader) ([]Transaction, error) {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	text := string(data)
+	var transactions []Transaction
+	seen := make(map[string]int)
+	for {
+		start := indexFold(text, "<STMTTRN>")
+		if start < 0 {
+			break
+		}
+		text = text[start+len("<STMTTRN>"):]
+		// a transaction ends where it is closed, or where the next begins
+		end := len(text)
+		for _, tag := range []string{"</STMTTRN>", "<STMTTRN>"} {
+			if i := indexFold(text, tag); i >= 0 && i < end {
+				end = i
+			}
+		}
+		fields := ofxFields(text[:end])
+		text = text[end:]
+		t := Transaction{ID: fields["FITID"], Description: strings.TrimSpace(fields["NAME"] + " " + fields["MEMO"])}
+		posted := fields["DTPOSTED"]
+		if len(posted) < 8 {
+			return nil, fmt.Errorf("transaction %d: DTPOSTED %q is not a date", len(transactions)+1, posted)
+		}
+		if t.Date, err = time.ParseInLocation("20060102", posted[:8], time.UTC); err != nil {
+			return nil, fmt.Errorf("transaction %d: DTPOSTED %q is not a date", len(transactions)+1, posted)
+		}
+		if t.Amount, err = parseStatementAmount(fields["TRNAMT"]); err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", len(transactions)+1, err)
+		}
+		transactions = append(transactions, t.withID(seen))
+	}
+	if transactions == nil && indexFold(string(data), "<OFX>") < 0 {
+		return nil, fmt.Errorf("not an OFX file")
+	}
+	return transactions, nil
+}
+
+// indexFold returns the index of the first substr in s, ignoring case, or -1
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ofxFields returns the value of each element in an OFX aggregate, whose
+// elements may be left unclosed, as they are in SGML
+func ofxFields(text string) map[string]string {
+	fields := make(map[string]string)
+	for _, part := range strings.Split(text, "<")[1:] {
+		i := strings.Index(part, ">")
+		if i < 0 || strings.HasPrefix(part, "/") {
+			continue
+		}
+		name := strings.ToUpper(part[:i])
+		if _, ok := fields[name]; !ok {
+			fields[name] = ofxUnescape(strings.TrimSpace(part[i+1:]))
+		}
+	}
+	return fields
+}
+
+var ofxUnescape = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">").Replace
@@ -0,0 +1,83 @@
+package booking
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadStatementCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		want    []Transaction
+		wantErr bool
+	}{
+		{"amount", "Date,Description,Amount\n2017-05-22,AIRBNB 6FBJUN1719,\"1,234.50\"\n",
+			[]Transaction{{ID: "2017-05-22 1234.50 AIRBNB 6FBJUN1719", Date: Datetime(2017, time.May, 22),
+				Amount: 1234.5, Description: "AIRBNB 6FBJUN1719"}}, false},
+		{"credit and debit", "id,Transaction Date,Memo,Debit,Credit\nT1,22/05/2017,BOOKING.COM,,£300.00\nT2,23/05/2017,RENT,50.00,\n",
+			[]Transaction{{ID: "T1", Date: Datetime(2017, time.May, 22), Amount: 300, Description: "BOOKING.COM"},
+				{ID: "T2", Date: Datetime(2017, time.May, 23), Amount: -50, Description: "RENT"}}, false},
+		{"identical rows", "Date,Description,Amount\n2017-05-22,AIRBNB,300\n2017-05-22,AIRBNB,300\n",
+			[]Transaction{{ID: "2017-05-22 300.00 AIRBNB", Date: Datetime(2017, time.May, 22), Amount: 300, Description: "AIRBNB"},
+				{ID: "2017-05-22 300.00 AIRBNB #2", Date: Datetime(2017, time.May, 22), Amount: 300, Description: "AIRBNB"}}, false},
+		{"no amount column", "Date,Description\n2017-05-22,AIRBNB\n", nil, true},
+		{"bad date", "Date,Description,Amount\n22nd May,AIRBNB,10\n", nil, true},
+		{"bad amount", "Date,Description,Amount\n2017-05-22,AIRBNB,ten\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadStatementCSV(strings.NewReader(tt.csv))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadStatementCSV() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ReadStatementCSV() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ReadStatementCSV()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadOFX(t *testing.T) {
+	sgml := `OFXHEADER:100
+DATA:OFXSGML
+
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><BANKTRANLIST>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20170522120000[0:GMT]<TRNAMT>350.00<FITID>A1<NAME>AIRBNB PAYMENTS<MEMO>6FBJUN1719
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20170523<TRNAMT>-12.50<FITID>A2<NAME>BANK CHARGES &amp; FEES
+</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+	xml := `<?xml version="1.0"?><OFX><BANKTRANLIST>
+<STMTTRN><TRNTYPE>CREDIT</TRNTYPE><DTPOSTED>20170522</DTPOSTED><TRNAMT>350.00</TRNAMT><FITID>A1</FITID>
+<NAME>AIRBNB PAYMENTS</NAME><MEMO>6FBJUN1719</MEMO></STMTTRN>
+<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20170523</DTPOSTED><TRNAMT>-12.50</TRNAMT><FITID>A2</FITID>
+<NAME>BANK CHARGES &amp; FEES</NAME></STMTTRN></BANKTRANLIST></OFX>`
+	want := []Transaction{
+		{ID: "A1", Date: Datetime(2017, time.May, 22), Amount: 350, Description: "AIRBNB PAYMENTS 6FBJUN1719"},
+		{ID: "A2", Date: Datetime(2017, time.May, 23), Amount: -12.5, Description: "BANK CHARGES & FEES"},
+	}
+	for name, ofx := range map[string]string{"sgml": sgml, "xml": xml} {
+		t.Run(name, func(t *testing.T) {
+			got, err := ReadOFX(strings.NewReader(ofx))
+			if err != nil {
+				t.Fatalf("ReadOFX() error = %v", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("ReadOFX() = %+v, want %+v", got, want)
+			}
+			for i := range got {
+				if got[i] != want[i] {
+					t.Errorf("ReadOFX()[%d] = %+v, want %+v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+	if _, err := ReadOFX(strings.NewReader("Date,Amount\n")); err == nil {
+		t.Errorf("ReadOFX() of a CSV did not fail")
+	}
+}
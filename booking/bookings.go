@@ -0,0 +1,976 @@
+// Package booking is the domain model of the holiday lettings business: its
+// properties and their settings, bookings, and how each booking is settled
+// between the guest, the channel, the agency and the house owner.
+//
+// The calculations make up its library API: CreateBooking works out a
+// Booking from a FormInput, Recalculate redoes it while keeping its history,
+// BookingRefFor and CreateBookingRef encode references and ParseBookingRef
+// decodes them, and GetBookingSpreadsheetRow and BookingSpreadsheetRow lay
+// bookings out as rows of the bookings spreadsheet, which ReadCSV, ParseCSV,
+// FixCSV and WriteSpreadsheet read and write.
+package booking
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateLayout is how dates are written, in CSVs, reports and forms
+const DateLayout = "2006-01-02"
+
+/*
+ * { "properties": [
+ *   { "long_name" : "FooBarBaz",
+ *     "short_name" : "FB",
+ *     "calendar" : "calendar@mycalendar.com",
+ *     "commission" : 0.1,
+ *     "booking_commission" : 0.0,
+ *     "house_owner_commission" : 0.1,
+ *     "greeting" : 15,
+ *     "laundry" : [10,10,15,15,25,25],
+ *     "cleaning" : 35,
+ *     "consumables" : [15,15,25,25,35,35]
+ *   },
+ *   { "long_name" : "WibbleWobbleWoo",
+ *     "short_name" : "WW",
+ *     "calendar" : "calendar2@mycalendar.com",
+ *     "commission" : 0.2,
+ *     "booking_commission" : 0.1,
+ *     "house_owner_commission" : 0.3,
+ *     "greeting" : 25,
+ *     "laundry" : [15,15,20,20,35,35],
+ *     "cleaning" : 35,
+ *     "consumables" : [15,15,25,25,35,35] }
+ * ]}
+ */
+
+// Property is used to unmarshal a JSON file of the above form
+type Property struct {
+	LongName             string    `json:"long_name"`
+	ShortName            string    `json:"short_name"`
+	Calendar             string    `json:"calendar"`
+	Commission           float64   `json:"commission"`
+	BookingCommission    float64   `json:"booking_commission"`
+	HouseOwnerCommission float64   `json:"house_owner_commission"`
+	Greeting             float64   `json:"greeting"`
+	Laundry              []float64 `json:"laundry"`
+	Cleaning             float64   `json:"cleaning"`
+	Consumables          []float64 `json:"consumables"`
+	// CancellationPolicy decides how much is refunded when a booking is cancelled
+	CancellationPolicy []CancellationTier `json:"cancellation_policy"`
+	// PaymentTerms decide when payment is due, per source
+	PaymentTerms []PaymentTerm `json:"payment_terms"`
+	// CommissionFreeSources are the sources the agency takes no commission on
+	CommissionFreeSources []string `json:"commission_free_sources"`
+	// Settlement decides how commissions are taken, see settlement.go
+	Settlement *Settlement `json:"settlement"`
+	// FeeRules replace the settlement and services above, see feerules.go
+	FeeRules []FeeRuleConfig `json:"fee_rules"`
+	// LaundryByParty and ConsumablesByParty replace Laundry and Consumables,
+	// see party.go
+	LaundryByParty     *PartyPricing `json:"laundry_by_party"`
+	ConsumablesByParty *PartyPricing `json:"consumables_by_party"`
+	// MaxOccupancy is the most people the property sleeps, or 0 for no limit
+	MaxOccupancy int `json:"max_occupancy"`
+	// PerNight prices services by the night as well, e.g. {"consumables": 2}
+	PerNight map[string]float64 `json:"per_night"`
+	// MidStayCleanEvery cleans during stays every so many nights, at the cleaning price
+	MidStayCleanEvery int `json:"mid_stay_clean_every"`
+	// LengthOfStay adjusts the price of services on longer stays, see stay.go
+	LengthOfStay []StayAdjustment `json:"length_of_stay"`
+	// Taxes are levied on the property's bookings, see tax.go
+	Taxes []TaxRule `json:"taxes"`
+	// Country is the ISO 3166 code of the country phone numbers without a
+	// country code are in, GB by default
+	Country string `json:"country"`
+	// TemplateDir holds the property's email templates, see mail.go
+	TemplateDir string `json:"template_dir"`
+	// Timezone, CheckIn and CheckOut are as the business's, which they
+	// default to, see clock.go
+	Timezone string `json:"timezone"`
+	CheckIn  string `json:"check_in"`
+	CheckOut string `json:"check_out"`
+}
+
+// Settings holds the settings for each property
+type Settings struct {
+	Properties []Property `json:"properties"`
+	// FeesBeforeDiscount charges commission and fees on the gross as it was
+	// before any discount, rather than on what the guest actually pays. A
+	// refund takes its share of the discount with it.
+	FeesBeforeDiscount bool `json:"fees_before_discount"`
+	// RetentionYears is how long guests' personal details are kept after
+	// they depart, see gdpr.go
+	RetentionYears int `json:"retention_years"`
+	// MailFrom is the address emails to guests are sent from
+	MailFrom string `json:"mail_from"`
+	// PreArrivalDays is how many days before arrival guests are reminded, 7 by default
+	PreArrivalDays int `json:"pre_arrival_days"`
+	// Timezone is the IANA name of the timezone the business is in, UTC by
+	// default, see clock.go
+	Timezone string `json:"timezone"`
+	// CheckIn and CheckOut are the times guests check in and out, e.g.
+	// "16:00" and "10:00", which they default to
+	CheckIn  string `json:"check_in"`
+	CheckOut string `json:"check_out"`
+	// References configures how booking references are written, see refs.go
+	References RefScheme `json:"references"`
+	// Owners are the house owners of the properties, see owner.go
+	Owners []HouseOwner `json:"owners"`
+}
+
+// Source is an Enum
+type Source int
+
+// BookingCom and others are sources the booking originated from
+const (
+	BookingCom Source = 1 + iota
+	AirBnb
+	Email
+	Phone
+	VisitBath
+	Other
+	nSources = int(Other)
+)
+
+var sourceNames = [nSources]string{"booking.com", "airbnb", "email", "phone", "visit", "other"}
+
+func (s Source) String() string {
+	if s < BookingCom || s > Other {
+		return ""
+	}
+	return sourceNames[s-1]
+}
+
+// ParseSource returns the Source with the given name, as written in the CSV
+func ParseSource(name string) (Source, bool) {
+	for x := BookingCom; x <= Other; x++ {
+		if x.String() == name {
+			return x, true
+		}
+	}
+	return Other, false
+}
+
+type Month int
+
+const (
+	Jan Month = 1 + iota
+	Feb
+	Mar
+	Apr
+	May
+	Jun
+	Jul
+	Aug
+	Sep
+	Oct
+	Nov
+	Dec
+	nMonths = int(Dec)
+)
+
+var abbrevMonths = map[string]Month{
+	"JAN": Jan,
+	"FEB": Feb,
+	"MAR": Mar,
+	"APR": Apr,
+	"MAY": May,
+	"JUN": Jun,
+	"JUL": Jul,
+	"AUG": Aug,
+	"SEP": Sep,
+	"OCT": Oct,
+	"NOV": Nov,
+	"DEC": Dec,
+}
+
+func (m Month) String() string {
+	foo := make(map[Month]string)
+	for k, v := range abbrevMonths {
+		foo[v] = k
+	}
+	return foo[m]
+}
+
+// FormInput holds all the form Inputs from the user
+type FormInput struct {
+	BookingRef     string
+	FirstName      string
+	LastName       string
+	Email          string
+	Mobile         string
+	Notes          string
+	Source         Source
+	NumberOfPeople int
+	Gross          float64
+	IsGreeting     bool
+	IsLaundry      bool
+	IsCleaning     bool
+	IsConsumables  bool
+	BookingDate    time.Time
+	Status         Status
+	// CancellationDate is when the guest cancelled, if they did
+	CancellationDate time.Time
+	Discount         Discount
+	// IsOwnerDirect is set for bookings the house owner took themselves
+	IsOwnerDirect bool
+	// Extras count things charged for per item, e.g. {"pets": 1}
+	Extras map[string]int
+	// NumberOfChildren counts the children among NumberOfPeople
+	NumberOfChildren int
+	// GuestID links the bookings of the same guest, see guest.go
+	GuestID string
+}
+
+// Booking holds a booking
+type Booking struct {
+	Form          FormInput
+	Property      Property
+	Arrival       time.Time
+	Departure     time.Time
+	BookingDate   time.Time
+	BookingFee    float64
+	HouseOwnerFee float64
+	Net           float64
+	TotalFees     float64
+	OwnerIncome   float64
+	// Retained is the part of the gross kept after any discount and refund
+	Retained       float64
+	Refund         float64
+	DiscountAmount float64
+	Amendments     []Amendment
+	DueDate        time.Time
+	IsCommission   bool
+	// AgencyCommission is the agency's cut, which is part of TotalFees
+	AgencyCommission float64
+	// LineItems itemise every deduction, in the order they were taken
+	LineItems []LineItem
+	// Taxes are levied on top of the gross and fees, see tax.go
+	Taxes []TaxLine
+	// Receipts are the payments for the booking found in the bank, see
+	// reconcile.go
+	Receipts []Receipt
+}
+
+// SpreadsheetRow holds a spreadsheet row
+type SpreadsheetRow struct {
+	BookingRef       string
+	PropertyLongName string
+	FirstName        string
+	LastName         string
+	Email            string
+	Mobile           string
+	Notes            string
+	BookingDate      time.Time
+	Source           Source
+	Arrival          time.Time
+	Departure        time.Time
+	NumberOfPeople   int
+	Gross            float64
+	Net              float64
+	IsDiscount       bool
+	Commission       float64
+	DueDate          time.Time
+	IsCommission     bool
+	Greeting         float64
+	Laundry          float64
+	Cleaning         float64
+	Consumables      float64
+	BookingFee       float64
+	HouseOwnerFee    float64
+	TotalFees        float64
+	OwnerIncome      float64
+	Status           Status
+	CancellationDate time.Time
+	Refund           float64
+	Discount         Discount
+	DiscountAmount   float64
+	AgencyCommission float64
+	Extras           map[string]int
+	Nights           int
+	// Services itemise the services charged for
+	Services         []LineItem
+	NumberOfChildren int
+	// TouristTax totals the taxes other than VAT
+	TouristTax float64
+	VAT        float64
+	GuestID    string
+	// IsOwnerDirect is set for bookings the house owner took themselves,
+	// which IsCommission alone cannot tell from commission free sources
+	IsOwnerDirect bool
+}
+
+// Spreadsheet holds a whole spreadsheet
+type Spreadsheet struct {
+	Rows []SpreadsheetRow
+}
+
+// GetSettings is used to unmarshal a JSON settings file into a Settings struct
+func GetSettings(jsonByteArray []byte) Settings {
+	var settings Settings
+	json.Unmarshal(jsonByteArray, &settings)
+	return settings
+}
+
+// LoadSettings unmarshals a JSON settings file like GetSettings, but returns
+// an error if it is not valid JSON or not valid settings
+func LoadSettings(jsonByteArray []byte) (Settings, error) {
+	var settings Settings
+	if err := json.Unmarshal(jsonByteArray, &settings); err != nil {
+		return settings, err
+	}
+	return settings, settings.Validate()
+}
+
+// Datetime is a utility function for making dates: midnight UTC, 0 hours,
+// 0 mins, 0 secs, 0 nanosecs, whatever the timezone, see clock.go
+func Datetime(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func yearsAfter(years int, fromDate time.Time) time.Time {
+	return fromDate.AddDate(years, 0, 0)
+}
+
+func getSliceEnd(bookingRef string) int {
+	var sliceEnd int
+	for i := 0; i < len(bookingRef); i++ {
+		_, err := strconv.Atoi(string(bookingRef[i]))
+		if err != nil {
+			break
+		} else {
+			sliceEnd++
+		}
+	}
+	return sliceEnd
+}
+
+func getBookingYear(sliceEnd int, bookingRef string, refs RefScheme) int {
+	number, _ := strconv.Atoi(bookingRef[:sliceEnd])
+	return refs.year(number)
+}
+
+// refPart returns bookingRef[from:to], or "" if the reference is too short
+func refPart(bookingRef string, from, to int) string {
+	if to > len(bookingRef) {
+		return ""
+	}
+	return bookingRef[from:to]
+}
+
+func getBookingProperty(sliceEnd int, bookingRef string, props []Property) Property {
+	var property Property
+	for p := range props {
+		property = props[p]
+		if props[p].ShortName == refPart(bookingRef, sliceEnd, sliceEnd+2) {
+			break
+		}
+	}
+	return property
+}
+
+func getBookingArrivalDate(sliceEnd int, bookingYear int, bookingRef string, refs RefScheme) time.Time {
+	dayStart := sliceEnd + 2 + refs.monthLen()
+	monthString := refPart(bookingRef, sliceEnd+2, dayStart)
+	day, _ := strconv.Atoi(refPart(bookingRef, dayStart, dayStart+2))
+	if month, ok := refs.month(monthString); ok {
+		return Datetime(bookingYear, month, day)
+	}
+	log.Println("unknown month in booking reference:", monthString)
+	return Datetime(0, 0, 0)
+}
+
+func getBookingDepartureDate(sliceEnd int, arrivalDate time.Time, bookingRef string, refs RefScheme) time.Time {
+	dayStart := sliceEnd + 4 + refs.monthLen()
+	dayString := refPart(bookingRef, dayStart, dayStart+2)
+	day, _ := strconv.Atoi(dayString)
+	month := arrivalDate.Month()
+	if day <= arrivalDate.Day() {
+		/* MAGIC: if `month +=1` is 13, time.Date handles this by rolling forward to next year */
+		month++
+	}
+	return Datetime(arrivalDate.Year(), month, day)
+}
+
+// ParseBookingRef decodes a booking reference into the property and dates of
+// the stay it is for, by the business's reference scheme, see refs.go.
+// Unlike CreateBooking, which makes what it can of any reference, it returns
+// an error for a reference that is not exactly the prefix, the year, a short
+// name of one of the properties, a month and two days that exist.
+func ParseBookingRef(bookingRef string, settings Settings) (Property, time.Time, time.Time, error) {
+	var property Property
+	var arrival, departure time.Time
+	refs := settings.References
+	if !strings.HasPrefix(bookingRef, refs.Prefix) {
+		return property, arrival, departure, fmt.Errorf("booking reference %q does not start with %q", bookingRef, refs.Prefix)
+	}
+	ref := refs.trimPrefix(bookingRef)
+	sliceEnd := getSliceEnd(ref)
+	if sliceEnd == 0 {
+		return property, arrival, departure, fmt.Errorf("booking reference %q does not have a year", bookingRef)
+	}
+	monthEnd := sliceEnd + 2 + refs.monthLen()
+	if len(ref) != monthEnd+4 {
+		return property, arrival, departure, fmt.Errorf("booking reference %q is not %d characters long",
+			bookingRef, len(refs.Prefix)+monthEnd+4)
+	}
+	if _, err := strconv.Atoi(ref[:sliceEnd]); err != nil {
+		return property, arrival, departure, fmt.Errorf("booking reference %q: %v", bookingRef, err)
+	}
+	shortName := ref[sliceEnd : sliceEnd+2]
+	found := false
+	for _, p := range settings.Properties {
+		if p.ShortName == shortName {
+			property, found = p, true
+			break
+		}
+	}
+	if !found {
+		return property, arrival, departure, fmt.Errorf("booking reference %q: no property %q", bookingRef, shortName)
+	}
+	if _, ok := refs.month(ref[sliceEnd+2 : monthEnd]); !ok {
+		return property, arrival, departure, fmt.Errorf("booking reference %q: no month %q", bookingRef, ref[sliceEnd+2:monthEnd])
+	}
+	arrivalDay, ok := refDay(ref[monthEnd : monthEnd+2])
+	departureDay, ok2 := refDay(ref[monthEnd+2 : monthEnd+4])
+	if !ok || !ok2 {
+		return property, arrival, departure, fmt.Errorf("booking reference %q: days must be from 01 to 31", bookingRef)
+	}
+	arrival = getBookingArrivalDate(sliceEnd, getBookingYear(sliceEnd, ref, refs), ref, refs)
+	departure = getBookingDepartureDate(sliceEnd, arrival, ref, refs)
+	if arrival.Day() != arrivalDay || departure.Day() != departureDay {
+		return property, arrival, departure, fmt.Errorf("booking reference %q: no such date", bookingRef)
+	}
+	return settings.localProperty(property), arrival, departure, nil
+}
+
+// refDay reads the two digit day of a booking reference
+func refDay(s string) (int, bool) {
+	if s[0] < '0' || s[0] > '9' || s[1] < '0' || s[1] > '9' {
+		return 0, false
+	}
+	day := int(s[0]-'0')*10 + int(s[1]-'0')
+	return day, day >= 1 && day <= 31
+}
+
+// CreateBooking works out the dates, fees, taxes and owner income of the
+// booking in a form, for the property its reference is for
+func CreateBooking(f FormInput, settings Settings) Booking {
+	refs := settings.References
+	ref := refs.trimPrefix(f.BookingRef)
+	sliceEnd := getSliceEnd(ref)
+	year := getBookingYear(sliceEnd, ref, refs)
+	arrival := getBookingArrivalDate(sliceEnd, year, ref, refs)
+	property := settings.localProperty(getBookingProperty(sliceEnd, ref, settings.Properties))
+	discount := f.Discount.AmountOff(f.Gross)
+	paid := f.Gross - discount
+	refunded := 0.0
+	if f.Status == Cancelled {
+		refunded = property.RefundFraction(f.CancellationDate, arrival)
+	}
+	refund := paid * refunded
+	retained := paid - refund
+	feeBase := retained
+	if settings.FeesBeforeDiscount {
+		// only the share of the discount on the part of the stay kept, so a
+		// booking refunded in full is charged nothing
+		feeBase += discount * (1 - refunded)
+	}
+	departure := getBookingDepartureDate(sliceEnd, arrival, ref, refs)
+	items := ApplyFeeRules(property.feeRules(), &FeeContext{
+		Form:      f,
+		Property:  property,
+		Arrival:   arrival,
+		Departure: departure,
+		Gross:     feeBase,
+		Net:       feeBase,
+	})
+	bookingFee := LineItemTotal(items, ChannelCommission)
+	net := retained - bookingFee
+	nights := NightsBetween(arrival, departure)
+	totalFees := 0.0
+	for _, item := range items {
+		totalFees += item.Amount
+	}
+	totalFees -= bookingFee
+	return Booking{
+		Form:             f,
+		Property:         property,
+		Arrival:          arrival,
+		Departure:        departure,
+		BookingDate:      f.BookingDate,
+		HouseOwnerFee:    LineItemTotal(items, HouseOwnerCommission),
+		BookingFee:       bookingFee,
+		Net:              net,
+		TotalFees:        totalFees,
+		OwnerIncome:      net - totalFees,
+		Retained:         retained,
+		Refund:           refund,
+		DiscountAmount:   discount,
+		DueDate:          property.paymentTerm(f.Source).dueDate(f.BookingDate, arrival, departure),
+		IsCommission:     property.agencyCommissionApplies(f),
+		AgencyCommission: LineItemTotal(items, AgencyCommission),
+		LineItems:        items,
+		Taxes:            property.taxLines(f, nights, retained, items),
+	}
+}
+
+// CreateBookingRef encodes the reference of a booking by the business's
+// reference scheme, see BookingRefFor
+func CreateBookingRef(b Booking, settings Settings) string {
+	refs := settings.References
+	return fmt.Sprintf("%s%d%s%s%.2d%.2d", refs.Prefix, refs.yearNumber(b.BookingDate.Year()),
+		b.Property.ShortName, refs.monthName(b.Arrival.Month()), b.Arrival.Day(), b.Departure.Day())
+}
+
+// GetBookingSpreadsheetRow works out the booking in a form as a spreadsheet row
+func GetBookingSpreadsheetRow(f FormInput, settings Settings) SpreadsheetRow {
+	return BookingSpreadsheetRow(CreateBooking(f, settings))
+}
+
+// BookingSpreadsheetRow lays a booking out as a spreadsheet row
+func BookingSpreadsheetRow(b Booking) SpreadsheetRow {
+	f := b.Form
+	row := SpreadsheetRow{
+		BookingRef:       f.BookingRef,
+		PropertyLongName: b.Property.LongName,
+		FirstName:        f.FirstName,
+		LastName:         f.LastName,
+		Email:            f.Email,
+		Mobile:           f.Mobile,
+		Notes:            f.Notes,
+		BookingDate:      f.BookingDate,
+		Source:           f.Source,
+		Arrival:          b.Arrival,
+		Departure:        b.Departure,
+		NumberOfPeople:   f.NumberOfPeople,
+		Gross:            f.Gross,
+		Net:              b.Net,
+		IsDiscount:       b.DiscountAmount > 0,
+		Commission:       b.Property.Commission,
+		DueDate:          b.DueDate,
+		IsCommission:     b.IsCommission,
+		Greeting:         b.Property.Greeting,
+		Laundry:          b.Property.laundryPricing().price(f.NumberOfPeople),
+		Cleaning:         b.Property.Cleaning,
+		Consumables:      b.Property.consumablesPricing().price(f.NumberOfPeople),
+		BookingFee:       b.BookingFee,
+		HouseOwnerFee:    b.HouseOwnerFee,
+		TotalFees:        b.TotalFees,
+		OwnerIncome:      b.OwnerIncome,
+		Status:           f.Status,
+		CancellationDate: f.CancellationDate,
+		Refund:           b.Refund,
+		Discount:         f.Discount,
+		DiscountAmount:   b.DiscountAmount,
+		AgencyCommission: b.AgencyCommission,
+		Extras:           f.Extras,
+		Nights:           NightsBetween(b.Arrival, b.Departure),
+		NumberOfChildren: f.NumberOfChildren,
+		TouristTax:       TaxTotal(b.Taxes, false),
+		VAT:              TaxTotal(b.Taxes, true),
+		GuestID:          f.GuestID,
+		IsOwnerDirect:    f.IsOwnerDirect,
+	}
+	for _, item := range b.LineItems {
+		if isServiceKind(item.Kind) {
+			row.Services = append(row.Services, item)
+		}
+	}
+	if !f.Status.takesPlace() {
+		row.Greeting, row.Laundry, row.Cleaning, row.Consumables = 0, 0, 0, 0
+	}
+	return row
+}
+
+// FixSpreadsheetRow feeds a bad spreadsheet row back into the calculation
+// to derive correct values based on settings
+func FixSpreadsheetRow(bad SpreadsheetRow, settings Settings) SpreadsheetRow {
+	f := FormInput{
+		BookingRef:       bad.BookingRef,
+		FirstName:        bad.FirstName,
+		LastName:         bad.LastName,
+		Email:            bad.Email,
+		Mobile:           bad.Mobile,
+		Notes:            bad.Notes,
+		Source:           bad.Source,
+		NumberOfPeople:   bad.NumberOfPeople,
+		BookingDate:      bad.BookingDate,
+		Gross:            bad.Gross,
+		IsGreeting:       true,
+		IsCleaning:       true,
+		IsLaundry:        true,
+		IsConsumables:    true,
+		Status:           bad.Status,
+		CancellationDate: bad.CancellationDate,
+		Discount:         bad.Discount,
+		IsOwnerDirect:    bad.IsOwnerDirect,
+		Extras:           bad.Extras,
+		NumberOfChildren: bad.NumberOfChildren,
+		GuestID:          bad.GuestID,
+	}
+	return GetBookingSpreadsheetRow(f, settings)
+}
+
+// parseCSVDate reads a YYYY-MM-DD date, returning the zero time for an empty one
+func parseCSVDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date := strings.Split(value, "-")
+	if len(date) != 3 {
+		return time.Time{}, fmt.Errorf("date %q is not year-month-day", value)
+	}
+	year, err := strconv.Atoi(date[0])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("date %q: %v", value, err)
+	}
+	month, err := strconv.Atoi(date[1])
+	if err != nil || month < 1 || month > 12 {
+		return time.Time{}, fmt.Errorf("date %q has no month %q", value, date[1])
+	}
+	day, err := strconv.Atoi(date[2])
+	if err != nil || day < 1 || day > 31 {
+		return time.Time{}, fmt.Errorf("date %q has no day %q", value, date[2])
+	}
+	return Datetime(year, time.Month(month), day), nil
+}
+
+// ParseCSV reads csv into FormInput manually.
+func ParseCSV(file string) ([]FormInput, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadCSV(f)
+}
+
+// csvColumns is how many columns a bookings CSV has at least, up to the gross
+const csvColumns = 13
+
+// ReadCSV reads the bookings in a bookings CSV, as written by hand up to the
+// gross column, or as written by WriteSpreadsheet. It returns the bookings
+// read before any row it cannot read, and an error saying which row that is.
+func ReadCSV(in io.Reader) ([]FormInput, error) {
+	var forms []FormInput
+	csvr := csv.NewReader(in)
+	for n := 1; ; n++ {
+		row, err := csvr.Read()
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return forms, err
+		}
+		if row[0] == csvHeader[0] {
+			// skip the header row of CSVs we wrote ourselves
+			continue
+		}
+		f, err := parseCSVRow(row)
+		if err != nil {
+			return forms, fmt.Errorf("row %d: %v", n, err)
+		}
+		forms = append(forms, f)
+	}
+}
+
+func parseCSVRow(row []string) (FormInput, error) {
+	var f FormInput
+	var err error
+	if len(row) < csvColumns {
+		return f, fmt.Errorf("%d columns, want at least %d", len(row), csvColumns)
+	}
+	f.BookingRef = row[0]
+	f.FirstName = row[2]
+	f.LastName = row[3]
+	f.Email = row[4]
+	f.Mobile = row[5]
+	f.Notes = row[6]
+	f.Source, _ = ParseSource(row[8])
+	f.NumberOfPeople, _ = strconv.Atoi(row[11])
+	if f.BookingDate, err = parseCSVDate(row[7]); err != nil {
+		return f, err
+	}
+	f.Gross, _ = strconv.ParseFloat(row[12], 64)
+	f.IsGreeting = true
+	f.IsLaundry = true
+	f.IsCleaning = true
+	f.IsConsumables = true
+	if len(row) > 38 {
+		f.IsOwnerDirect = strings.EqualFold(row[38], "TRUE")
+	} else if len(row) > 17 {
+		// CSVs written before is_owner_direct only say if commission was taken
+		f.IsOwnerDirect = strings.EqualFold(row[17], "FALSE")
+	}
+	if len(row) > 27 {
+		f.Status, _ = ParseStatus(row[26])
+		if f.CancellationDate, err = parseCSVDate(row[27]); err != nil {
+			return f, err
+		}
+	}
+	if len(row) > 42 {
+		if f.Discount.Kind, _ = ParseDiscountKind(row[41]); f.Discount.Kind != NoDiscount {
+			f.Discount.Amount, _ = strconv.ParseFloat(row[42], 64)
+		}
+	} else if len(row) > 29 {
+		// CSVs written before discount_kind only have the amount taken off
+		if amount, _ := strconv.ParseFloat(row[29], 64); amount != 0 {
+			f.Discount = Discount{Kind: FixedDiscount, Amount: amount}
+		}
+	}
+	if len(row) > 31 {
+		f.Extras = parseExtras(row[31])
+	}
+	if len(row) > 34 {
+		f.NumberOfChildren, _ = strconv.Atoi(row[34])
+	}
+	if len(row) > 37 {
+		f.GuestID = row[37]
+	}
+	if len(row) > 40 {
+		f.Discount.Reason = row[39]
+		f.Discount.ApprovedBy = row[40]
+	}
+	return f, nil
+}
+
+// FixCSV fixes a CSV!
+func FixCSV(file string, settings Settings) Spreadsheet {
+	var rows []SpreadsheetRow
+	lines, err := ParseCSV(file)
+	if err != nil {
+		// fix the rows before the one that could not be read
+		log.Println(file+":", err)
+	}
+	for i := 0; i < len(lines); i++ {
+		rows = append(rows,
+			FixSpreadsheetRow(
+				// first we derive the data using the correct calculations,
+				GetBookingSpreadsheetRow(lines[i], settings),
+				// then we do any fixing in FixSpreadsheetRow as necessary,
+				// e.g. using different settings.
+				// this currently just uses the same settings
+				settings))
+	}
+	return Spreadsheet{Rows: rows}
+}
+
+var csvHeader = []string{"booking_ref", "property", "first_name", "last_name", "email",
+	"mobile", "notes", "booking_date", "source", "arrival_date", "departure_date",
+	"number_of_people", "gross", "net", "is_discount", "commission", "due_date",
+	"is_commission", "greeting", "laundry", "cleaning", "consumables", "booking_fee",
+	"house_owner_fee", "total_fees", "owner_income", "status", "cancellation_date",
+	"refund", "discount_amount", "agency_commission", "extras",
+	"nights", "services", "number_of_children", "tourist_tax", "vat", "guest_id",
+	"is_owner_direct", "discount_reason", "discount_approved_by",
+	// the discount as agreed: a fraction of the gross, or a sum of money
+	"discount_kind", "discount"}
+
+// ImportProblem is something wrong with one booking in an imported CSV
+type ImportProblem struct {
+	// Row counts the bookings in the CSV from 1, not counting any header
+	Row        int
+	BookingRef string
+	Err        error
+}
+
+func (p ImportProblem) String() string {
+	return fmt.Sprintf("row %d (%s): %v", p.Row, p.BookingRef, p.Err)
+}
+
+// ImportReport says what happened to the bookings in an imported CSV
+type ImportReport struct {
+	Imported int
+	// Rejected bookings were not saved
+	Rejected []ImportProblem
+	// Warnings are about bookings that were saved, e.g. with a malformed email address
+	Warnings []ImportProblem
+}
+
+// ImportCSV calculates each booking in a bookings CSV and saves it in the
+// store, replacing any stored booking with the same reference. Bookings that
+// cannot be taken, e.g. for more people than the property sleeps or with a
+// reference that is not for a stay at one of the properties, are
+// rejected and left out of the store. Guests' contact details are normalised,
+// with a warning for any that cannot be, and bookings are linked to the
+// guests already in the store, or in earlier rows, with the same details.
+func ImportCSV(file string, settings Settings, store BookingStore) (ImportReport, error) {
+	var report ImportReport
+	forms, err := ParseCSV(file)
+	if err != nil {
+		return report, err
+	}
+	stored, err := store.List(BookingFilter{})
+	if err != nil {
+		return report, err
+	}
+	guests := newGuestIndex(stored)
+	for i, f := range forms {
+		if _, _, _, err = ParseBookingRef(f.BookingRef, settings); err != nil {
+			report.Rejected = append(report.Rejected, ImportProblem{Row: i + 1, BookingRef: f.BookingRef, Err: err})
+			continue
+		}
+		b := CreateBooking(f, settings)
+		if err = b.Property.CheckPartySize(f.NumberOfPeople); err != nil {
+			report.Rejected = append(report.Rejected, ImportProblem{Row: i + 1, BookingRef: f.BookingRef, Err: err})
+			continue
+		}
+		for _, w := range b.Property.NormaliseContact(&b.Form) {
+			report.Warnings = append(report.Warnings, ImportProblem{Row: i + 1, BookingRef: f.BookingRef, Err: w})
+		}
+		guests.link(&b)
+		err = store.Create(b)
+		if err == ErrBookingExists {
+			var old Booking
+			if old, err = store.Get(f.BookingRef); err == nil {
+				b.Amendments = old.Amendments
+				b.Receipts = old.Receipts
+				err = store.Update(b)
+			}
+		}
+		if err != nil {
+			return report, err
+		}
+		report.Imported++
+	}
+	return report, nil
+}
+
+// ExportCSV writes the bookings in the store that match filter to a CSV file
+func ExportCSV(file string, store BookingStore, filter BookingFilter) error {
+	bookings, err := store.List(filter)
+	if err != nil {
+		return err
+	}
+	var spreadsheet Spreadsheet
+	for _, b := range bookings {
+		spreadsheet.Rows = append(spreadsheet.Rows, BookingSpreadsheetRow(b))
+	}
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err = WriteSpreadsheet(out, spreadsheet); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// WriteFixedCSV writes fixed CSV to a new CSV
+func WriteFixedCSV(spreadsheet Spreadsheet) {
+	file, _ := os.Create("out.csv")
+	defer file.Close()
+	WriteSpreadsheet(file, spreadsheet)
+}
+
+// formatExtras writes extras as e.g. "cots=1;pets=2", sorted by item
+func formatExtras(extras map[string]int) string {
+	var items []string
+	for item, n := range extras {
+		items = append(items, fmt.Sprintf("%s=%d", item, n))
+	}
+	sort.Strings(items)
+	return strings.Join(items, ";")
+}
+
+// formatLineItems writes line items as e.g. "cleaning=35.00;laundry=10.00"
+func formatLineItems(items []LineItem) string {
+	var pairs []string
+	for _, item := range items {
+		pairs = append(pairs, fmt.Sprintf("%s=%.2f", item.Name, item.Amount))
+	}
+	return strings.Join(pairs, ";")
+}
+
+// parseExtras reads extras written by formatExtras
+func parseExtras(value string) map[string]int {
+	if value == "" {
+		return nil
+	}
+	extras := make(map[string]int)
+	for _, pair := range strings.Split(value, ";") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		if n, err := strconv.Atoi(kv[1]); err == nil {
+			extras[kv[0]] = n
+		}
+	}
+	return extras
+}
+
+// formatCSVDate writes a YYYY-MM-DD date, leaving the zero time empty
+func formatCSVDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
+
+// WriteSpreadsheet writes a spreadsheet as CSV, header first
+func WriteSpreadsheet(out io.Writer, spreadsheet Spreadsheet) error {
+	w := csv.NewWriter(out)
+	w.Write(csvHeader)
+	s := spreadsheet.Rows
+	for i := 0; i < len(s); i++ {
+		var row []string
+		row = append(row, s[i].BookingRef)
+		row = append(row, s[i].PropertyLongName)
+		row = append(row, s[i].FirstName)
+		row = append(row, s[i].LastName)
+		row = append(row, s[i].Email)
+		row = append(row, s[i].Mobile)
+		row = append(row, s[i].Notes)
+		row = append(row, s[i].BookingDate.Format("2006-01-02"))
+		row = append(row, s[i].Source.String())
+		row = append(row, s[i].Arrival.Format("2006-01-02"))
+		row = append(row, s[i].Departure.Format("2006-01-02"))
+		row = append(row, fmt.Sprintf("%d", s[i].NumberOfPeople))
+		row = append(row, fmt.Sprintf("%.2f", s[i].Gross))
+		row = append(row, fmt.Sprintf("%.2f", s[i].Net))
+		row = append(row, strings.ToUpper(strconv.FormatBool(s[i].IsDiscount)))
+		row = append(row, fmt.Sprintf("%.3f", s[i].Commission))
+		row = append(row, s[i].DueDate.Format("2006-01-02"))
+		row = append(row, strings.ToUpper(strconv.FormatBool(s[i].IsCommission)))
+		row = append(row, fmt.Sprintf("%.2f", s[i].Greeting))
+		row = append(row, fmt.Sprintf("%.2f", s[i].Laundry))
+		row = append(row, fmt.Sprintf("%.2f", s[i].Cleaning))
+		row = append(row, fmt.Sprintf("%.2f", s[i].Consumables))
+		row = append(row, fmt.Sprintf("%.2f", s[i].BookingFee))
+		row = append(row, fmt.Sprintf("%.2f", s[i].HouseOwnerFee))
+		row = append(row, fmt.Sprintf("%.2f", s[i].TotalFees))
+		row = append(row, fmt.Sprintf("%.2f", s[i].OwnerIncome))
+		row = append(row, s[i].Status.String())
+		row = append(row, formatCSVDate(s[i].CancellationDate))
+		row = append(row, fmt.Sprintf("%.2f", s[i].Refund))
+		row = append(row, fmt.Sprintf("%.2f", s[i].DiscountAmount))
+		row = append(row, fmt.Sprintf("%.2f", s[i].AgencyCommission))
+		row = append(row, formatExtras(s[i].Extras))
+		row = append(row, fmt.Sprintf("%d", s[i].Nights))
+		row = append(row, formatLineItems(s[i].Services))
+		row = append(row, fmt.Sprintf("%d", s[i].NumberOfChildren))
+		row = append(row, fmt.Sprintf("%.2f", s[i].TouristTax))
+		row = append(row, fmt.Sprintf("%.2f", s[i].VAT))
+		row = append(row, s[i].GuestID)
+		row = append(row, strings.ToUpper(strconv.FormatBool(s[i].IsOwnerDirect)))
+		row = append(row, s[i].Discount.Reason)
+		row = append(row, s[i].Discount.ApprovedBy)
+		row = append(row, s[i].Discount.Kind.String())
+		row = append(row, strconv.FormatFloat(s[i].Discount.Amount, 'f', -1, 64))
+		w.Write(row)
+	}
+	w.Flush()
+	return w.Error()
+}
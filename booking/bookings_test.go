@@ -0,0 +1,281 @@
+package booking
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_yearsAfter(t *testing.T) {
+	type args struct {
+		years    int
+		fromDate time.Time
+	}
+	tests := []struct {
+		name string
+		args args
+		want time.Time
+	}{
+		{"", args{years: 0, fromDate: Datetime(2017, time.January, 1)}, Datetime(2017, time.January, 1)},
+		{"", args{years: 1, fromDate: Datetime(2017, time.January, 1)}, Datetime(2018, time.January, 1)},
+		//TODO: we don't want to accept negative values
+		{"", args{years: -1, fromDate: Datetime(2017, time.January, 1)}, Datetime(2016, time.January, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := yearsAfter(tt.args.years, tt.args.fromDate); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("yearsAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getSliceEnd(t *testing.T) {
+	type args struct {
+		bookingRef string
+	}
+	tests := []struct {
+		name string
+		args args
+		want int
+	}{
+		// TODO raise error if first value is NaN
+		{"", args{bookingRef: "AMJUN1719"}, 0},
+		{"", args{bookingRef: "1AMJUN1719"}, 1},
+		{"", args{bookingRef: "11AMJUN1719"}, 2},
+		{"", args{bookingRef: "111AMJUN1719"}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getSliceEnd(tt.args.bookingRef); got != tt.want {
+				t.Errorf("getSliceEnd() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getBookingYear(t *testing.T) {
+	type args struct {
+		sliceEnd   int
+		bookingRef string
+	}
+	tests := []struct {
+		name string
+		args args
+		want int
+	}{
+		{"", args{sliceEnd: 1, bookingRef: "6ASJUN1719"}, 2017},
+		// TODO: raise error if slice causes a read of NaN
+		{"", args{sliceEnd: 2, bookingRef: "6ASJUN1719"}, defaultOpeningDate.Year()},
+		{"", args{sliceEnd: 3, bookingRef: "123JUN1719"}, 2134},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getBookingYear(tt.args.sliceEnd, tt.args.bookingRef, RefScheme{}); got != tt.want {
+				t.Errorf("getBookingYear() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getBookingArrivalDate(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	type args struct {
+		sliceEnd    int
+		bookingYear int
+		bookingRef  string
+	}
+	tests := []struct {
+		name string
+		args args
+		want time.Time
+	}{
+		{"", args{sliceEnd: 1, bookingRef: "1ASJUN1719", bookingYear: 2012}, Datetime(2012, time.June, 17)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getBookingArrivalDate(tt.args.sliceEnd, tt.args.bookingYear, tt.args.bookingRef, RefScheme{}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getBookingArrivalDate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	got := getBookingArrivalDate(1, 2012, "1ASJUB1719", RefScheme{})
+	want := Datetime(0, 0, 0)
+	wantLog := "unknown month in booking reference: JUB\n"
+	gotLog := buf.String()
+	ignore := len("2017/12/10 12:37:08 ")
+	if gotLog[ignore:] != wantLog {
+		t.Errorf("%#v, want %#v", gotLog[ignore:], wantLog)
+	}
+	if got != want {
+		t.Errorf("%#v, want %#v", got, want)
+	}
+}
+
+func Test_getBookingDepartureDate(t *testing.T) {
+	type args struct {
+		sliceEnd    int
+		arrivalDate time.Time
+		bookingRef  string
+	}
+	tests := []struct {
+		name string
+		args args
+		want time.Time
+	}{
+		{"", args{sliceEnd: 1, bookingRef: "1ASJUN1719",
+			arrivalDate: Datetime(2012, time.June, 17)},
+			Datetime(2012, time.June, 19)},
+		{"", args{sliceEnd: 1, bookingRef: "1ASDEC3103",
+			arrivalDate: Datetime(2012, time.December, 31)},
+			Datetime(2013, time.January, 3)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getBookingDepartureDate(tt.args.sliceEnd, tt.args.arrivalDate, tt.args.bookingRef, RefScheme{}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getBookingDepartureDate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_createBookingRef(t *testing.T) {
+	type args struct {
+		b Booking
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{"", args{b: Booking{
+			Property:    Property{ShortName: "AM"},
+			Arrival:     Datetime(2012, time.December, 17),
+			Departure:   Datetime(2013, time.December, 03),
+			BookingDate: Datetime(2012, time.May, 20)}},
+			"1AMDEC1703"},
+		{"", args{b: Booking{
+			Property:    Property{ShortName: "AS"},
+			Arrival:     Datetime(2012, time.June, 17),
+			Departure:   Datetime(2012, time.June, 19),
+			BookingDate: Datetime(2017, time.May, 20)}},
+			"6ASJUN1719"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CreateBookingRef(tt.args.b, Settings{}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CreateBookingRef() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBookingRef(t *testing.T) {
+	tests := []struct {
+		ref       string
+		property  string
+		arrival   time.Time
+		departure time.Time
+		wantErr   bool
+	}{
+		{"6FBJUN1719", "FB", Datetime(2017, time.June, 17), Datetime(2017, time.June, 19), false},
+		{"6WWDEC3003", "WW", Datetime(2017, time.December, 30), Datetime(2018, time.January, 3), false},
+		{"10FBFEB2702", "FB", Datetime(2021, time.February, 27), Datetime(2021, time.March, 2), false},
+		{"", "", time.Time{}, time.Time{}, true},
+		{"6", "", time.Time{}, time.Time{}, true},
+		{"6FB", "", time.Time{}, time.Time{}, true},
+		{"6FBJUN17", "", time.Time{}, time.Time{}, true},
+		{"FBJUN1719", "", time.Time{}, time.Time{}, true},
+		{"6FBJUN1719X", "", time.Time{}, time.Time{}, true},
+		{"6XXJUN1719", "", time.Time{}, time.Time{}, true},
+		{"6FBJUB1719", "", time.Time{}, time.Time{}, true},
+		{"6FBJUN0019", "", time.Time{}, time.Time{}, true},
+		{"6FBJUN1732", "", time.Time{}, time.Time{}, true},
+		{"6FBJUN+119", "", time.Time{}, time.Time{}, true},
+		{"6FBFEB3003", "", time.Time{}, time.Time{}, true},
+		{"6FBFEB2531", "", time.Time{}, time.Time{}, true},
+		{"99999999999999999999FBJUN1719", "", time.Time{}, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			property, arrival, departure, err := ParseBookingRef(tt.ref, testSettings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBookingRef() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if property.ShortName != tt.property || !arrival.Equal(tt.arrival) || !departure.Equal(tt.departure) {
+				t.Errorf("ParseBookingRef() = %s %v %v, want %s %v %v",
+					property.ShortName, arrival, departure, tt.property, tt.arrival, tt.departure)
+			}
+		})
+	}
+}
+
+func TestCreateBookingMalformedRef(t *testing.T) {
+	// CreateBooking makes what it can of a reference, but must not panic
+	for _, ref := range []string{"", "6", "6F", "6FBJU", "6FBJUN1", "6FBJUN171"} {
+		CreateBooking(FormInput{BookingRef: ref, NumberOfPeople: 1}, testSettings)
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+	}{
+		{"short row", "6FBJUN1719,FooBarBaz,Ann\n"},
+		{"no month", "6FBJUN1719,FooBarBaz,Ann,Smith,,,,2017-13-01,email,,,2,400\n"},
+		{"no day", "6FBJUN1719,FooBarBaz,Ann,Smith,,,,2017-06-xx,email,,,2,400\n"},
+		{"not a date", "6FBJUN1719,FooBarBaz,Ann,Smith,,,,yesterday,email,,,2,400\n"},
+		{"unquoted quote", "6FBJUN1719,Foo\"Bar,Ann,Smith,,,,2017-06-01,email,,,2,400\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadCSV(bytes.NewBufferString(tt.csv)); err == nil {
+				t.Errorf("ReadCSV(%q) did not fail", tt.csv)
+			}
+		})
+	}
+	forms, err := ReadCSV(bytes.NewBufferString(
+		"6FBJUN1719,FooBarBaz,Ann,Smith,,,,2017-6-1,email,,,2,400\n" +
+			"6FBJUN2022,FooBarBaz,Bob,Jones,,,,2017-06-02,email,,,2\n"))
+	if err == nil || len(forms) != 1 || !forms[0].BookingDate.Equal(Datetime(2017, time.June, 1)) {
+		t.Errorf("ReadCSV() = %v, %v, want the first row and an error for the second", forms, err)
+	}
+}
+
+func TestWriteSpreadsheet_ownerDirect(t *testing.T) {
+	settings := Settings{Properties: append([]Property(nil), testSettings.Properties...)}
+	settings.Properties[0].CommissionFreeSources = []string{"phone"}
+	forms := []FormInput{
+		{BookingRef: "6FBJUN1719", Source: Email, NumberOfPeople: 2, Gross: 400, IsOwnerDirect: true},
+		{BookingRef: "6FBJUL0108", Source: Phone, NumberOfPeople: 2, Gross: 400},
+		{BookingRef: "6FBAUG0205", Source: Email, NumberOfPeople: 2, Gross: 400},
+	}
+	var spreadsheet Spreadsheet
+	for _, f := range forms {
+		spreadsheet.Rows = append(spreadsheet.Rows, GetBookingSpreadsheetRow(f, settings))
+	}
+	var out bytes.Buffer
+	if err := WriteSpreadsheet(&out, spreadsheet); err != nil {
+		t.Fatalf("WriteSpreadsheet() error = %v", err)
+	}
+	read, err := ReadCSV(&out)
+	if err != nil || len(read) != len(forms) {
+		t.Fatalf("ReadCSV() = %d bookings, %v, want %d", len(read), err, len(forms))
+	}
+	for i, f := range forms {
+		if read[i].IsOwnerDirect != f.IsOwnerDirect {
+			t.Errorf("ReadCSV() %s IsOwnerDirect = %v, want %v", f.BookingRef, read[i].IsOwnerDirect, f.IsOwnerDirect)
+		}
+		if fixed := FixSpreadsheetRow(spreadsheet.Rows[i], settings); fixed.IsOwnerDirect != f.IsOwnerDirect {
+			t.Errorf("FixSpreadsheetRow() %s IsOwnerDirect = %v, want %v", f.BookingRef, fixed.IsOwnerDirect, f.IsOwnerDirect)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package booking
+
+import (
+	"fmt"
+	"time"
+)
+
+/*
+ * Dates, such as a booking's arrival and departure, are calendar days: they
+ * are made with Datetime, at midnight UTC, wherever the properties are.
+ * Times of day are in the timezone of the business, or of a property, which
+ * also has check-in and check-out times for calendar exports:
+ *
+ * { "timezone" : "Europe/London",
+ *   "check_in" : "16:00",
+ *   "check_out" : "10:00",
+ *   "properties": [
+ *     { "short_name" : "FB",
+ *       "timezone" : "Europe/Paris",
+ *       "check_in" : "15:00",
+ *       ...
+ *
+ * A property takes the business's settings for any it leaves out, and the
+ * business defaults to UTC, checking in at 16:00 and out at 10:00.
+ *
+ * Nothing reads the clock itself: the time now is passed in, from a Clock
+ * that is time.Now outside of tests, and Settings.Today says what day it is.
+ */
+
+// Clock tells the time now
+type Clock func() time.Time
+
+const (
+	defaultCheckIn  = "16:00"
+	defaultCheckOut = "10:00"
+	clockLayout     = "15:04"
+)
+
+// ParseDate reads a YYYY-MM-DD date, as written by DateLayout
+func ParseDate(value string) (time.Time, error) {
+	return time.ParseInLocation(DateLayout, value, time.UTC)
+}
+
+// loadLocation loads a timezone by its IANA name, with UTC for ""
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// Location returns the timezone of the business, UTC if it has none or it
+// cannot be loaded
+func (s Settings) Location() *time.Location {
+	loc, err := loadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Today returns what day it is for the business at now
+func (s Settings) Today(now time.Time) time.Time {
+	year, month, day := now.In(s.Location()).Date()
+	return Datetime(year, month, day)
+}
+
+// localProperty fills in the timezone and check-in and check-out times a
+// property leaves out with the business's
+func (s Settings) localProperty(p Property) Property {
+	if p.Timezone == "" {
+		p.Timezone = s.Timezone
+	}
+	if p.CheckIn == "" {
+		p.CheckIn = s.CheckIn
+	}
+	if p.CheckOut == "" {
+		p.CheckOut = s.CheckOut
+	}
+	return p
+}
+
+// Location returns the timezone of the property, UTC if it has none or it
+// cannot be loaded
+func (p Property) Location() *time.Location {
+	loc, err := loadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// at returns the time of day clock, e.g. "16:00", on date at the property
+func (p Property) at(date time.Time, clock, fallback string) time.Time {
+	t, err := time.Parse(clockLayout, clock)
+	if err != nil {
+		t, _ = time.Parse(clockLayout, fallback)
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, p.Location())
+}
+
+// CheckInOn returns when guests check in to the property on date
+func (p Property) CheckInOn(date time.Time) time.Time {
+	return p.at(date, p.CheckIn, defaultCheckIn)
+}
+
+// CheckOutOn returns when guests check out of the property on date
+func (p Property) CheckOutOn(date time.Time) time.Time {
+	return p.at(date, p.CheckOut, defaultCheckOut)
+}
+
+// CheckIn returns when the guests check in, on the day of arrival
+func (b Booking) CheckIn() time.Time {
+	return b.Property.CheckInOn(b.Arrival)
+}
+
+// CheckOut returns when the guests check out, on the day of departure
+func (b Booking) CheckOut() time.Time {
+	return b.Property.CheckOutOn(b.Departure)
+}
+
+// checkTimes checks a timezone and check-in and check-out times can be read
+func checkTimes(timezone, checkIn, checkOut string) error {
+	if _, err := loadLocation(timezone); err != nil {
+		return fmt.Errorf("timezone %q: %v", timezone, err)
+	}
+	for _, clock := range []string{checkIn, checkOut} {
+		if _, err := time.Parse(clockLayout, clock); clock != "" && err != nil {
+			return fmt.Errorf("time %q is not HH:MM", clock)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package booking
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSettingsToday(t *testing.T) {
+	london := Settings{Timezone: "Europe/London"}
+	tests := []struct {
+		name     string
+		settings Settings
+		now      time.Time
+		want     time.Time
+	}{
+		{"UTC", Settings{}, time.Date(2017, time.June, 17, 23, 30, 0, 0, time.UTC), Datetime(2017, time.June, 17)},
+		// half past midnight in summer time is still the day before in UTC
+		{"BST", london, time.Date(2017, time.June, 17, 23, 30, 0, 0, time.UTC), Datetime(2017, time.June, 18)},
+		{"GMT", london, time.Date(2017, time.December, 17, 23, 30, 0, 0, time.UTC), Datetime(2017, time.December, 17)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.settings.Today(tt.now); !got.Equal(tt.want) {
+				t.Errorf("Today() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBookingCheckInOut(t *testing.T) {
+	settings := Settings{Timezone: "Europe/London", CheckIn: "15:00", Properties: []Property{
+		{ShortName: "FB", LongName: "FooBarBaz"},
+		{ShortName: "WW", LongName: "WibbleWobbleWoo", Timezone: "Europe/Paris", CheckOut: "11:30"},
+	}}
+	tests := []struct {
+		ref      string
+		checkIn  time.Time
+		checkOut time.Time
+	}{
+		// the clocks go back on 29 October 2017
+		{"6FBOCT2730", time.Date(2017, time.October, 27, 14, 0, 0, 0, time.UTC), time.Date(2017, time.October, 30, 10, 0, 0, 0, time.UTC)},
+		{"6WWJUN1719", time.Date(2017, time.June, 17, 13, 0, 0, 0, time.UTC), time.Date(2017, time.June, 19, 9, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			b := CreateBooking(FormInput{BookingRef: tt.ref, NumberOfPeople: 1}, settings)
+			if !b.CheckIn().Equal(tt.checkIn) || !b.CheckOut().Equal(tt.checkOut) {
+				t.Errorf("CheckIn(), CheckOut() = %v, %v, want %v, %v", b.CheckIn().UTC(), b.CheckOut().UTC(), tt.checkIn, tt.checkOut)
+			}
+		})
+	}
+	// without any settings, guests check in at 16:00 and out at 10:00 UTC
+	b := CreateBooking(FormInput{BookingRef: "6FBJUN1719", NumberOfPeople: 1}, testSettings)
+	if want := time.Date(2017, time.June, 17, 16, 0, 0, 0, time.UTC); !b.CheckIn().Equal(want) {
+		t.Errorf("CheckIn() = %v, want %v", b.CheckIn(), want)
+	}
+}
+
+func TestValidateTimes(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		wantErr  bool
+	}{
+		{"defaults", Settings{}, false},
+		{"configured", Settings{Timezone: "Europe/London", CheckIn: "15:00", CheckOut: "10:30"}, false},
+		{"unknown timezone", Settings{Timezone: "Europe/Bath"}, true},
+		{"bad check-in", Settings{CheckIn: "3pm"}, true},
+		{"unknown property timezone", Settings{Properties: []Property{{ShortName: "FB", Timezone: "Mars/Olympus"}}}, true},
+		{"bad property check-out", Settings{Properties: []Property{{ShortName: "FB", CheckOut: "25:00"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.settings.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidEmail is returned for email addresses that are not well formed
+var ErrInvalidEmail = errors.New("not a valid email address")
+
+// country returns the country the property's guests' phone numbers are taken
+// to be in when they have no country code
+func (p Property) country() string {
+	if p.Country == "" {
+		return defaultCountry
+	}
+	return p.Country
+}
+
+// CheckEmail returns an error if email is not a single, bare address with a
+// dotted domain, e.g. "ann@example.com". It does not check the address exists.
+func CheckEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return ErrInvalidEmail
+	}
+	at := strings.LastIndex(email, "@")
+	if domain := email[at+1:]; !strings.Contains(domain, ".") || strings.HasSuffix(domain, ".") {
+		return ErrInvalidEmail
+	}
+	return nil
+}
+
+// normaliseName trims a name and title-cases it if it was written all in
+// capitals or all in lower case. Mixed case, as in "McDonald", is kept.
+func normaliseName(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if name != strings.ToUpper(name) && name != strings.ToLower(name) {
+		return name
+	}
+	runes := []rune(strings.ToLower(name))
+	for i, r := range runes {
+		if i == 0 || runes[i-1] == ' ' || runes[i-1] == '-' || runes[i-1] == '\'' {
+			runes[i] = unicode.ToUpper(r)
+		}
+	}
+	return string(runes)
+}
+
+// NormaliseContact tidies the guest's names and contact details in place: it
+// title-cases the names, lower-cases the email address and writes the mobile
+// number in E.164 form. It returns what it could not make sense of, leaving
+// those details as they were entered.
+func (p Property) NormaliseContact(f *FormInput) []error {
+	var warnings []error
+	f.FirstName = normaliseName(f.FirstName)
+	f.LastName = normaliseName(f.LastName)
+	f.Email = NormaliseEmail(f.Email)
+	if f.Email != "" {
+		if err := CheckEmail(f.Email); err != nil {
+			warnings = append(warnings, fmt.Errorf("email %q: %v", f.Email, err))
+		}
+	}
+	f.Mobile = strings.TrimSpace(f.Mobile)
+	if mobile, err := NormalisePhone(f.Mobile, p.country()); err != nil {
+		warnings = append(warnings, fmt.Errorf("mobile %q: %v", f.Mobile, err))
+	} else {
+		f.Mobile = mobile
+	}
+	return warnings
+}
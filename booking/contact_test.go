@@ -0,0 +1,75 @@
+package booking
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_checkEmail(t *testing.T) {
+	for email, valid := range map[string]bool{
+		"ann@example.com":           true,
+		"ann.smith+bath@mail.co.uk": true,
+		"ann@example":               false,
+		"ann@example.":              false,
+		"ann.example.com":           false,
+		"Ann <ann@example.com>":     false,
+		"ann@@example.com":          false,
+	} {
+		if err := CheckEmail(email); (err == nil) != valid {
+			t.Errorf("CheckEmail(%q) error = %v, want valid %v", email, err, valid)
+		}
+	}
+}
+
+func Test_normaliseName(t *testing.T) {
+	for name, want := range map[string]string{
+		"  ann  ":         "Ann",
+		"MARY-JANE SMITH": "Mary-Jane Smith",
+		"o'brien":         "O'Brien",
+		"McDonald":        "McDonald",
+	} {
+		if got := normaliseName(name); got != want {
+			t.Errorf("normaliseName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestProperty_normaliseContact(t *testing.T) {
+	f := FormInput{FirstName: " ANN ", LastName: "smith", Email: " Ann@Example.COM", Mobile: "06 12 34 56 78"}
+	if warnings := (Property{Country: "FR"}).NormaliseContact(&f); len(warnings) != 0 {
+		t.Errorf("NormaliseContact() warnings = %v", warnings)
+	}
+	want := FormInput{FirstName: "Ann", LastName: "Smith", Email: "ann@example.com", Mobile: "+33612345678"}
+	if !reflect.DeepEqual(f, want) {
+		t.Errorf("NormaliseContact() = %+v, want %+v", f, want)
+	}
+	f = FormInput{Email: "ann at example", Mobile: "call reception"}
+	if warnings := (Property{}).NormaliseContact(&f); len(warnings) != 2 {
+		t.Errorf("NormaliseContact() warnings = %v, want 2", warnings)
+	}
+	if f.Mobile != "call reception" {
+		t.Errorf("NormaliseContact() mobile = %q, want it kept as entered", f.Mobile)
+	}
+}
+
+func TestImportCSV_warnings(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "bookings.csv")
+	csv := "6FBJUN0103,FooBarBaz,ann,SMITH,ann@example,07700 900123,,2017-05-01,email,,,2,500\n"
+	if err := os.WriteFile(file, []byte(csv), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store := NewMemoryStore()
+	report, err := ImportCSV(file, testSettings, store)
+	if err != nil {
+		t.Fatalf("ImportCSV() error = %v", err)
+	}
+	if report.Imported != 1 || len(report.Warnings) != 1 || report.Warnings[0].Row != 1 {
+		t.Errorf("ImportCSV() = %+v, want 1 imported with 1 warning", report)
+	}
+	b, _ := store.Get("6FBJUN0103")
+	if b.Form.FirstName != "Ann" || b.Form.LastName != "Smith" || b.Form.Mobile != "+447700900123" {
+		t.Errorf("ImportCSV() guest = %s %s %s", b.Form.FirstName, b.Form.LastName, b.Form.Mobile)
+	}
+}
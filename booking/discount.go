@@ -0,0 +1,57 @@
+package booking
+
+import "math"
+
+// DiscountKind is how a discount is expressed
+type DiscountKind int
+
+// NoDiscount and others are the kinds of discount. The zero DiscountKind is
+// NoDiscount, so a zero Discount takes nothing off.
+const (
+	NoDiscount DiscountKind = iota
+	PercentDiscount
+	FixedDiscount
+	nDiscountKinds = int(FixedDiscount) + 1
+)
+
+var discountKindNames = [nDiscountKinds]string{"none", "percent", "fixed"}
+
+func (k DiscountKind) String() string {
+	if k < 0 || int(k) >= nDiscountKinds {
+		return ""
+	}
+	return discountKindNames[k]
+}
+
+// ParseDiscountKind returns the DiscountKind with the given name
+func ParseDiscountKind(name string) (DiscountKind, bool) {
+	for x := NoDiscount; int(x) < nDiscountKinds; x++ {
+		if x.String() == name {
+			return x, true
+		}
+	}
+	return NoDiscount, false
+}
+
+// Discount is a reduction on the gross agreed with the guest
+type Discount struct {
+	Kind DiscountKind
+	// Amount is a fraction of the gross for a PercentDiscount, e.g. 0.1 for
+	// 10% off, or a sum of money for a FixedDiscount
+	Amount     float64
+	Reason     string
+	ApprovedBy string
+}
+
+// AmountOff returns how much the discount takes off the gross, which is never
+// more than the gross itself
+func (d Discount) AmountOff(gross float64) float64 {
+	var off float64
+	switch d.Kind {
+	case PercentDiscount:
+		off = d.Amount * gross
+	case FixedDiscount:
+		off = d.Amount
+	}
+	return math.Max(0, math.Min(gross, off))
+}
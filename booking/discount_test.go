@@ -0,0 +1,124 @@
+package booking
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiscount_amountOff(t *testing.T) {
+	tests := []struct {
+		name     string
+		discount Discount
+		gross    float64
+		want     float64
+	}{
+		{"none", Discount{}, 400, 0},
+		{"percent", Discount{Kind: PercentDiscount, Amount: 0.1}, 400, 40},
+		{"fixed", Discount{Kind: FixedDiscount, Amount: 25}, 400, 25},
+		{"more than gross", Discount{Kind: FixedDiscount, Amount: 500}, 400, 400},
+		{"negative", Discount{Kind: FixedDiscount, Amount: -5}, 400, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.discount.AmountOff(tt.gross); got != tt.want {
+				t.Errorf("AmountOff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_createBooking_discount(t *testing.T) {
+	f := FormInput{BookingRef: "6WWJUN1719", Source: AirBnb, NumberOfPeople: 2, Gross: 1000,
+		Discount: Discount{Kind: PercentDiscount, Amount: 0.1, Reason: "repeat guest", ApprovedBy: "Jo"}}
+	tests := []struct {
+		name               string
+		feesBeforeDiscount bool
+		// cancelled, if set, is when the guest cancelled the stay, which
+		// arrives 2017-06-17, for a full refund 30 days ahead and half after
+		cancelled         time.Time
+		wantBookingFee    float64
+		wantNet           float64
+		wantHouseOwnerFee float64
+	}{
+		// WW takes a 10% booking commission, 20% agency commission and 30%
+		// house owner commission, each on the net
+		{"after discount", false, time.Time{}, 90, 810, 194.4},
+		{"before discount", true, time.Time{}, 100, 800, 216},
+		// only the discount on what is kept of the stay is added back
+		{"before discount, half refunded", true, Datetime(2017, time.June, 1), 50, 400, 108},
+		{"before discount, refunded", true, Datetime(2017, time.January, 1), 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := testSettings
+			settings.FeesBeforeDiscount = tt.feesBeforeDiscount
+			f := f
+			if !tt.cancelled.IsZero() {
+				f.Status, f.CancellationDate = Cancelled, tt.cancelled
+				settings.Properties = append([]Property(nil), testSettings.Properties...)
+				settings.Properties[1].CancellationPolicy = policySettings.Properties[0].CancellationPolicy
+			}
+			b := CreateBooking(f, settings)
+			if b.DiscountAmount != 100 {
+				t.Errorf("CreateBooking() discount = %v, want 100", b.DiscountAmount)
+			}
+			if math.Abs(b.BookingFee-tt.wantBookingFee) > 1e-9 {
+				t.Errorf("CreateBooking() booking fee = %v, want %v", b.BookingFee, tt.wantBookingFee)
+			}
+			if math.Abs(b.Net-tt.wantNet) > 1e-9 {
+				t.Errorf("CreateBooking() net = %v, want %v", b.Net, tt.wantNet)
+			}
+			if math.Abs(b.HouseOwnerFee-tt.wantHouseOwnerFee) > 1e-9 {
+				t.Errorf("CreateBooking() house owner fee = %v, want %v", b.HouseOwnerFee, tt.wantHouseOwnerFee)
+			}
+			row := BookingSpreadsheetRow(b)
+			if !row.IsDiscount || row.DiscountAmount != 100 {
+				t.Errorf("spreadsheet row IsDiscount = %v, DiscountAmount = %v", row.IsDiscount, row.DiscountAmount)
+			}
+			var out bytes.Buffer
+			if err := WriteSpreadsheet(&out, Spreadsheet{Rows: []SpreadsheetRow{row}}); err != nil {
+				t.Fatalf("WriteSpreadsheet() error = %v", err)
+			}
+			read, err := ReadCSV(&out)
+			if err != nil || len(read) != 1 {
+				t.Fatalf("ReadCSV() = %v, %v, want the booking", read, err)
+			}
+			if read[0].Discount != f.Discount {
+				t.Errorf("ReadCSV() discount = %+v, want %+v", read[0].Discount, f.Discount)
+			}
+		})
+	}
+}
+
+func TestReadCSV_discount(t *testing.T) {
+	tests := []struct {
+		name     string
+		discount Discount
+	}{
+		{"none", Discount{}},
+		{"percent", Discount{Kind: PercentDiscount, Amount: 0.125, Reason: "repeat guest", ApprovedBy: "Jo"}},
+		{"fixed", Discount{Kind: FixedDiscount, Amount: 25.5, Reason: "late check-in", ApprovedBy: "Sam"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := FormInput{BookingRef: "6FBJUN1719", Source: Email, NumberOfPeople: 2, Gross: 400, Discount: tt.discount}
+			var out bytes.Buffer
+			WriteSpreadsheet(&out, Spreadsheet{Rows: []SpreadsheetRow{GetBookingSpreadsheetRow(f, testSettings)}})
+			read, err := ReadCSV(&out)
+			if err != nil || len(read) != 1 || read[0].Discount != tt.discount {
+				t.Errorf("ReadCSV() = %+v, %v, want discount %+v", read, err, tt.discount)
+			}
+		})
+	}
+	// CSVs written before discount_kind keep only the amount taken off
+	old := "6FBJUN1719,FooBarBaz,Ann,Smith,,,,2017-06-01,email,,,2,400" + strings.Repeat(",", 17)
+	for amount, want := range map[string]Discount{"0.00": {}, "40.00": {Kind: FixedDiscount, Amount: 40}} {
+		read, err := ReadCSV(strings.NewReader(old + amount + "\n"))
+		if err != nil || len(read) != 1 || read[0].Discount != want {
+			t.Errorf("ReadCSV() with discount_amount %s = %+v, %v, want discount %+v", amount, read, err, want)
+		}
+	}
+}
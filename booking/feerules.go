@@ -0,0 +1,387 @@
+package booking
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+/*
+ * A property's fees are worked out by a list of fee rules, applied in order.
+ * Properties without "fee_rules" use the settlement model and the services in
+ * their other settings; one with them describes every fee itself, e.g.
+ *
+ * "fee_rules": [
+ *   { "type" : "channel_commission", "rate" : 0.1, "source_rates" : { "booking.com" : 0.15 } },
+ *   { "type" : "agency_commission", "rate" : 0.1 },
+ *   { "type" : "house_owner_commission", "rate" : 0.1, "minimum" : 35 },
+ *   { "type" : "per_stay", "name" : "laundry", "service" : "laundry", "by_people" : [10,10,15,15,25,25] },
+ *   { "type" : "per_stay", "name" : "consumables", "service" : "consumables",
+ *     "by_party" : { "bands" : [ { "max_people" : 4, "amount" : 25 } ], "extra_person" : 5 } },
+ *   { "type" : "per_stay", "name" : "cleaning", "service" : "cleaning", "amount" : 35 },
+ *   { "type" : "per_night", "name" : "heating", "amount" : 5 },
+ *   { "type" : "per_person", "name" : "towels", "amount" : 2 },
+ *   { "type" : "per_item", "name" : "pets", "item" : "pets", "amount" : 20 },
+ *   { "type" : "mid_stay", "name" : "mid_stay_cleaning", "service" : "cleaning", "amount" : 35, "every_nights" : 7 },
+ *   { "type" : "length_of_stay", "adjustments" : [ { "min_nights" : 14, "adjustment" : -0.1 } ] }
+ * ]
+ */
+
+// The types of fee rule, which are also the kinds of line item they make
+const (
+	ChannelCommission    = "channel_commission"
+	AgencyCommission     = "agency_commission"
+	HouseOwnerCommission = "house_owner_commission"
+	PerStay              = "per_stay"
+	PerNight             = "per_night"
+	PerPerson            = "per_person"
+	PerItem              = "per_item"
+	MidStay              = "mid_stay"
+	LengthOfStay         = "length_of_stay"
+)
+
+// isServiceKind reports whether line items of the kind charge for services,
+// rather than take commission
+func isServiceKind(kind string) bool {
+	switch kind {
+	case PerStay, PerNight, PerPerson, PerItem, MidStay, LengthOfStay:
+		return true
+	}
+	return false
+}
+
+// LineItem is one amount deducted from a booking
+type LineItem struct {
+	Name string
+	// Kind is the type of rule that made the item
+	Kind string
+	// Service is what a service item charges for, e.g. "cleaning" for both
+	// "cleaning" and "cleaning_per_night": the rule's service, or its name
+	// if it has none
+	Service string
+	// Rate times Base is Amount: a commission rate and what it is charged on,
+	// or a price and how many of it are charged for
+	Rate   float64
+	Base   float64
+	Amount float64
+	// EveryNights is how often a MidStay service is done, so the jobs
+	// planned are those charged for
+	EveryNights int
+}
+
+// LineItemTotal returns the total of the line items of the given kind
+func LineItemTotal(items []LineItem, kind string) float64 {
+	total := 0.0
+	for _, item := range items {
+		if item.Kind == kind {
+			total += item.Amount
+		}
+	}
+	return total
+}
+
+// FeeContext is the booking fee rules are charging for
+type FeeContext struct {
+	Form      FormInput
+	Property  Property
+	Arrival   time.Time
+	Departure time.Time
+	// Gross is what commissions are charged on
+	Gross float64
+	// Net is what is left of Gross after the commissions taken so far.
+	// Commission rules take their amount off it.
+	Net float64
+	// Items are the line items of the rules applied so far
+	Items []LineItem
+}
+
+// nights returns the length of the stay
+func (ctx *FeeContext) nights() int {
+	return NightsBetween(ctx.Arrival, ctx.Departure)
+}
+
+// FeeRule contributes line items to a booking
+type FeeRule interface {
+	Apply(ctx *FeeContext) []LineItem
+}
+
+// ApplyFeeRules applies each rule in turn, returning all their line items
+func ApplyFeeRules(rules []FeeRule, ctx *FeeContext) []LineItem {
+	for _, rule := range rules {
+		ctx.Items = append(ctx.Items, rule.Apply(ctx)...)
+	}
+	return ctx.Items
+}
+
+// CommissionRule takes a rate of the gross or net of a booking
+type CommissionRule struct {
+	// Kind is ChannelCommission, AgencyCommission or HouseOwnerCommission
+	Kind string
+	Name string
+	Rate float64
+	// SourceRates replace Rate for bookings from the named sources
+	SourceRates map[string]float64
+	Deduction
+}
+
+// Apply takes the commission off the context's net
+func (r CommissionRule) Apply(ctx *FeeContext) []LineItem {
+	if r.Kind == AgencyCommission && !ctx.Property.agencyCommissionApplies(ctx.Form) {
+		return nil
+	}
+	rate := r.Rate
+	if sourceRate, ok := r.SourceRates[ctx.Form.Source.String()]; ok {
+		rate = sourceRate
+	}
+	base := ctx.Net
+	if r.Basis == "gross" {
+		base = ctx.Gross
+	}
+	amount := rate * base
+	// minimums only apply to stays that take place
+	if ctx.Form.Status.takesPlace() {
+		amount = math.Max(r.Minimum, amount)
+	}
+	ctx.Net -= amount
+	return []LineItem{{Name: r.Name, Kind: r.Kind, Rate: rate, Base: base, Amount: amount}}
+}
+
+// ServiceRule charges a price per stay, night, person or item, or for each
+// service done during a stay
+type ServiceRule struct {
+	// Kind is PerStay, PerNight, PerPerson, PerItem or MidStay
+	Kind string
+	Name string
+	// Service, if set, only charges guests who asked for that service:
+	// "greeting", "laundry", "cleaning" or "consumables"
+	Service string
+	Amount  float64
+	// ByParty, if set, prices by party size instead of Amount
+	ByParty *PartyPricing
+	// Item is the entry in FormInput.Extras a PerItem rule counts, e.g. "pets"
+	Item string
+	// EveryNights is how often a MidStay service is done, e.g. a clean every
+	// 7 nights. There is none on the day of departure.
+	EveryNights int
+}
+
+var serviceNames = []string{"greeting", "laundry", "cleaning", "consumables"}
+
+func isService(name string) bool {
+	for _, s := range serviceNames {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// wantsService reports whether the guest asked for the named service.
+// Every guest gets the unnamed service.
+func (f FormInput) wantsService(service string) bool {
+	switch service {
+	case "greeting":
+		return f.IsGreeting
+	case "laundry":
+		return f.IsLaundry
+	case "cleaning":
+		return f.IsCleaning
+	case "consumables":
+		return f.IsConsumables
+	}
+	return true
+}
+
+// Apply charges for the service, if the stay takes place
+func (r ServiceRule) Apply(ctx *FeeContext) []LineItem {
+	if !ctx.Form.Status.takesPlace() || !ctx.Form.wantsService(r.Service) {
+		return nil
+	}
+	price := r.Amount
+	if r.ByParty != nil {
+		price = r.ByParty.price(ctx.Form.NumberOfPeople)
+	}
+	quantity := 1
+	switch r.Kind {
+	case PerNight:
+		quantity = ctx.nights()
+	case PerPerson:
+		quantity = ctx.Form.NumberOfPeople
+	case PerItem:
+		quantity = ctx.Form.Extras[r.Item]
+	case MidStay:
+		quantity = 0
+		if r.EveryNights > 0 && ctx.nights() > 0 {
+			quantity = (ctx.nights() - 1) / r.EveryNights
+		}
+	}
+	if quantity <= 0 {
+		return nil
+	}
+	service := r.Service
+	if service == "" {
+		service = r.Name
+	}
+	item := LineItem{Name: r.Name, Kind: r.Kind, Service: service, Rate: price, Base: float64(quantity),
+		Amount: price * float64(quantity)}
+	if r.Kind == MidStay {
+		item.EveryNights = r.EveryNights
+	}
+	return []LineItem{item}
+}
+
+// FeeRuleConfig is a fee rule as written in settings.json
+type FeeRuleConfig struct {
+	Type        string             `json:"type"`
+	Name        string             `json:"name"`
+	Rate        float64            `json:"rate"`
+	SourceRates map[string]float64 `json:"source_rates"`
+	Basis       string             `json:"basis"`
+	Minimum     float64            `json:"minimum"`
+	Service     string             `json:"service"`
+	Amount      float64            `json:"amount"`
+	ByPeople    []float64          `json:"by_people"`
+	ByParty     *PartyPricing      `json:"by_party"`
+	Item        string             `json:"item"`
+	EveryNights int                `json:"every_nights"`
+	Adjustments []StayAdjustment   `json:"adjustments"`
+}
+
+// rule builds the fee rule the config describes
+func (c FeeRuleConfig) rule() (FeeRule, error) {
+	name := c.Name
+	if name == "" {
+		name = c.Type
+	}
+	switch c.Type {
+	case ChannelCommission, AgencyCommission, HouseOwnerCommission:
+		return CommissionRule{Kind: c.Type, Name: name, Rate: c.Rate, SourceRates: c.SourceRates,
+			Deduction: Deduction{Basis: c.Basis, Minimum: c.Minimum}}, nil
+	case PerItem:
+		if c.Item == "" {
+			return nil, fmt.Errorf("fee rule %q: per_item rules need an item", name)
+		}
+		fallthrough
+	case PerStay, PerNight, PerPerson, MidStay:
+		if c.Type == MidStay && c.EveryNights < 1 {
+			return nil, fmt.Errorf("fee rule %q: mid_stay rules need every_nights", name)
+		}
+		if c.Service != "" && !isService(c.Service) {
+			return nil, fmt.Errorf("fee rule %q: unknown service %q", name, c.Service)
+		}
+		byParty := c.ByParty
+		if byParty == nil && len(c.ByPeople) > 0 {
+			pricing := pricingByPeople(c.ByPeople)
+			byParty = &pricing
+		}
+		return ServiceRule{Kind: c.Type, Name: name, Service: c.Service, Amount: c.Amount,
+			ByParty: byParty, Item: c.Item, EveryNights: c.EveryNights}, nil
+	case LengthOfStay:
+		return LengthOfStayRule{Name: name, Adjustments: c.Adjustments}, nil
+	}
+	return nil, fmt.Errorf("fee rule %q: unknown type %q", name, c.Type)
+}
+
+// defaultFeeRules are the fee rules of a property without its own: the
+// settlement model followed by the property's services
+func (p Property) defaultFeeRules() []FeeRule {
+	s := p.settlement()
+	laundry, consumables := p.laundryPricing(), p.consumablesPricing()
+	rules := []FeeRule{
+		CommissionRule{Kind: ChannelCommission, Name: "channel_fee", Rate: p.BookingCommission,
+			SourceRates: map[string]float64{BookingCom.String(): 0.15}, Deduction: s.ChannelFee},
+		CommissionRule{Kind: AgencyCommission, Name: "agency_commission", Rate: p.Commission,
+			Deduction: s.AgencyCommission},
+		CommissionRule{Kind: HouseOwnerCommission, Name: "house_owner_commission", Rate: p.HouseOwnerCommission,
+			Deduction: s.HouseOwnerCommission},
+		ServiceRule{Kind: PerStay, Name: "consumables", Service: "consumables", ByParty: &consumables},
+		ServiceRule{Kind: PerStay, Name: "laundry", Service: "laundry", ByParty: &laundry},
+		ServiceRule{Kind: PerStay, Name: "greeting", Service: "greeting", Amount: p.Greeting},
+		ServiceRule{Kind: PerStay, Name: "cleaning", Service: "cleaning", Amount: p.Cleaning},
+	}
+	for _, service := range serviceNames {
+		if price, ok := p.PerNight[service]; ok {
+			rules = append(rules, ServiceRule{Kind: PerNight, Name: service + "_per_night", Service: service, Amount: price})
+		}
+	}
+	if p.MidStayCleanEvery > 0 {
+		rules = append(rules, ServiceRule{Kind: MidStay, Name: "mid_stay_cleaning", Service: "cleaning",
+			Amount: p.Cleaning, EveryNights: p.MidStayCleanEvery})
+	}
+	if len(p.LengthOfStay) > 0 {
+		rules = append(rules, LengthOfStayRule{Name: "length_of_stay", Adjustments: p.LengthOfStay})
+	}
+	return rules
+}
+
+// feeRules returns the property's fee rules. Rules that are misconfigured
+// are logged and skipped; Settings.Validate reports them up front.
+func (p Property) feeRules() []FeeRule {
+	if len(p.FeeRules) == 0 {
+		return p.defaultFeeRules()
+	}
+	var rules []FeeRule
+	for _, c := range p.FeeRules {
+		rule, err := c.rule()
+		if err != nil {
+			log.Println(p.ShortName, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// Validate checks every property's fee rules can be built, and that it only
+// prices known services by the night, levies known types of tax, has email
+// templates that parse and is in a known timezone, that booking references
+// can be written, and that house owners own known properties
+func (s Settings) Validate() error {
+	if err := checkTimes(s.Timezone, s.CheckIn, s.CheckOut); err != nil {
+		return err
+	}
+	if err := s.References.validate(); err != nil {
+		return err
+	}
+	for _, p := range s.Properties {
+		if err := checkTimes(p.Timezone, p.CheckIn, p.CheckOut); err != nil {
+			return fmt.Errorf("property %s: %v", p.ShortName, err)
+		}
+		for _, c := range p.FeeRules {
+			if _, err := c.rule(); err != nil {
+				return fmt.Errorf("property %s: %v", p.ShortName, err)
+			}
+		}
+		for service := range p.PerNight {
+			if !isService(service) {
+				return fmt.Errorf("property %s: unknown per night service %q", p.ShortName, service)
+			}
+		}
+		for _, t := range p.Taxes {
+			if err := t.validate(); err != nil {
+				return fmt.Errorf("property %s: %v", p.ShortName, err)
+			}
+		}
+		if err := p.checkEmailTemplates(); err != nil {
+			return fmt.Errorf("property %s: %v", p.ShortName, err)
+		}
+	}
+	return s.validateOwners()
+}
+
+// ExtraItems returns every item a per_item fee rule charges for, across all
+// properties, in the order they are first configured
+func (s Settings) ExtraItems() []string {
+	var items []string
+	seen := make(map[string]bool)
+	for _, p := range s.Properties {
+		for _, c := range p.FeeRules {
+			if c.Type == PerItem && c.Item != "" && !seen[c.Item] {
+				seen[c.Item] = true
+				items = append(items, c.Item)
+			}
+		}
+	}
+	return items
+}
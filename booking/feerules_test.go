@@ -0,0 +1,87 @@
+package booking
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const petFriendlySettings = `{ "properties": [
+  { "long_name" : "DogHouse",
+    "short_name" : "DH",
+    "fee_rules" : [
+      { "type" : "channel_commission", "rate" : 0.1, "source_rates" : { "booking.com" : 0.2 } },
+      { "type" : "house_owner_commission", "rate" : 0.1, "minimum" : 35 },
+      { "type" : "per_stay", "name" : "cleaning", "service" : "cleaning", "amount" : 40 },
+      { "type" : "per_night", "name" : "heating", "amount" : 5 },
+      { "type" : "per_person", "name" : "towels", "amount" : 2 },
+      { "type" : "per_item", "name" : "pets", "item" : "pets", "amount" : 20 }
+    ]
+  }
+]}`
+
+func TestFeeRuleConfig_rule(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  FeeRuleConfig
+		want    FeeRule
+		wantErr bool
+	}{
+		{"commission", FeeRuleConfig{Type: HouseOwnerCommission, Rate: 0.1, Minimum: 35},
+			CommissionRule{Kind: HouseOwnerCommission, Name: HouseOwnerCommission, Rate: 0.1,
+				Deduction: Deduction{Minimum: 35}}, false},
+		{"service", FeeRuleConfig{Type: PerNight, Name: "heating", Amount: 5},
+			ServiceRule{Kind: PerNight, Name: "heating", Amount: 5}, false},
+		{"unknown type", FeeRuleConfig{Type: "per_fortnight"}, nil, true},
+		{"unknown service", FeeRuleConfig{Type: PerStay, Service: "butler"}, nil, true},
+		{"item missing", FeeRuleConfig{Type: PerItem, Amount: 20}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.config.rule()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("rule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rule() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_createBooking_feeRules(t *testing.T) {
+	settings := GetSettings([]byte(petFriendlySettings))
+	if err := settings.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	f := FormInput{BookingRef: "6DHJUN1724", Source: BookingCom, NumberOfPeople: 3, Gross: 1000,
+		IsCleaning: true, Extras: map[string]int{"pets": 2}, BookingDate: Datetime(2017, time.May, 1)}
+	b := CreateBooking(f, settings)
+	want := []LineItem{
+		{Name: ChannelCommission, Kind: ChannelCommission, Rate: 0.2, Base: 1000, Amount: 200},
+		{Name: HouseOwnerCommission, Kind: HouseOwnerCommission, Rate: 0.1, Base: 800, Amount: 80},
+		{Name: "cleaning", Kind: PerStay, Service: "cleaning", Rate: 40, Base: 1, Amount: 40},
+		{Name: "heating", Kind: PerNight, Service: "heating", Rate: 5, Base: 7, Amount: 35},
+		{Name: "towels", Kind: PerPerson, Service: "towels", Rate: 2, Base: 3, Amount: 6},
+		{Name: "pets", Kind: PerItem, Service: "pets", Rate: 20, Base: 2, Amount: 40},
+	}
+	if !reflect.DeepEqual(b.LineItems, want) {
+		t.Errorf("CreateBooking() line items = %v, want %v", b.LineItems, want)
+	}
+	if b.BookingFee != 200 || b.Net != 800 || b.TotalFees != 201 || b.OwnerIncome != 599 {
+		t.Errorf("CreateBooking() booking fee, net, total fees, owner income = %v, %v, %v, %v, want 200, 800, 201, 599",
+			b.BookingFee, b.Net, b.TotalFees, b.OwnerIncome)
+	}
+}
+
+func TestSettings_Validate(t *testing.T) {
+	var settings Settings
+	json.Unmarshal([]byte(`{"properties": [{"short_name": "XX", "fee_rules": [{"type": "per_fortnight"}]}]}`), &settings)
+	if err := settings.Validate(); err == nil {
+		t.Errorf("Validate() of an unknown fee rule type succeeded")
+	}
+	if got := GetSettings([]byte(petFriendlySettings)).ExtraItems(); !reflect.DeepEqual(got, []string{"pets"}) {
+		t.Errorf("ExtraItems() = %v, want [pets]", got)
+	}
+}
@@ -0,0 +1,117 @@
+package booking
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// The fuzz targets are seeded from the references used in the other tests
+// and from the golden files; run one with e.g.
+//
+//	go test -run '^$' -fuzz FuzzParseBookingRef ./booking
+
+func FuzzParseBookingRef(f *testing.F) {
+	for _, ref := range []string{
+		"6FBJUN1719", "6WWJUL0108", "6FBAUG0205", "6FBDEC3003", "1ASJUB1719",
+		"AMJUN1719", "111AMJUN1719", "6FBFEB3003", "", "6", "6FB", "6FBJUN17",
+	} {
+		f.Add(ref)
+	}
+	f.Fuzz(func(t *testing.T, ref string) {
+		// CreateBooking makes what it can of any reference
+		b := CreateBooking(FormInput{BookingRef: ref, NumberOfPeople: 1}, testSettings)
+		property, arrival, departure, err := ParseBookingRef(ref, testSettings)
+		if err != nil {
+			return
+		}
+		if !departure.After(arrival) {
+			t.Errorf("%q departs %v, not after it arrives %v", ref, departure, arrival)
+		}
+		if b.Property.ShortName != property.ShortName || !b.Arrival.Equal(arrival) || !b.Departure.Equal(departure) {
+			t.Errorf("%q parses to %s %v %v but CreateBooking makes %s %v %v", ref,
+				property.ShortName, arrival, departure, b.Property.ShortName, b.Arrival, b.Departure)
+		}
+	})
+}
+
+func FuzzReadCSV(f *testing.F) {
+	addGoldenSeeds(f, "bookings.csv", "want.csv")
+	f.Add([]byte("6FBJUN1719,FooBarBaz,Ann,Smith,,,,2017-06-01,email,,,2,400\n"))
+	f.Add([]byte("6FBJUN1719,FooBarBaz,Ann\n"))
+	f.Add([]byte("6FBJUN1719,FooBarBaz,Ann,Smith,,,,2017--01,email,,,2,400\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		forms, err := ReadCSV(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		for _, form := range forms {
+			// a booking read without error can be worked out and written
+			WriteSpreadsheet(ioutil.Discard, Spreadsheet{Rows: []SpreadsheetRow{GetBookingSpreadsheetRow(form, testSettings)}})
+		}
+	})
+}
+
+func FuzzLoadSettings(f *testing.F) {
+	addGoldenSeeds(f, "settings.json")
+	f.Add([]byte(petFriendlySettings))
+	f.Add([]byte(`{"properties": [{"short_name": "FB", "laundry": []}]}`))
+	f.Add([]byte(`{"properties": null}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		settings, err := LoadSettings(data)
+		if err != nil {
+			return
+		}
+		// valid settings can work out a booking at each of their properties
+		for _, p := range settings.Properties {
+			if len(p.ShortName) != 2 {
+				continue
+			}
+			CreateBooking(FormInput{BookingRef: "6" + p.ShortName + "JUN1719", NumberOfPeople: 8,
+				IsGreeting: true, IsLaundry: true, IsCleaning: true, IsConsumables: true}, settings)
+		}
+	})
+}
+
+func FuzzReadStatement(f *testing.F) {
+	f.Add([]byte("Date,Description,Amount\n2017-05-22,AIRBNB 6FBJUN1719,\"1,234.50\"\n"), false)
+	f.Add([]byte("id,Transaction Date,Memo,Debit,Credit\nT1,22/05/2017,BOOKING.COM,,£300.00\n"), false)
+	f.Add([]byte("<OFX><STMTTRN><DTPOSTED>20170522<TRNAMT>350.00<FITID>A1<NAME>AIRBNB</STMTTRN></OFX>"), true)
+	f.Add([]byte("<OFX><STMTTRN><DTPOSTED>2017<TRNAMT>x<STMTTRN>"), true)
+	f.Fuzz(func(t *testing.T, data []byte, ofx bool) {
+		read := ReadStatementCSV
+		if ofx {
+			read = ReadOFX
+		}
+		transactions, err := read(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		// a statement read without error can be reconciled
+		store := NewMemoryStore()
+		for _, b := range testBookings() {
+			store.Create(b)
+		}
+		if _, err := Reconcile(store, transactions, DefaultReconcileWindow); err != nil {
+			t.Errorf("Reconcile() error = %v", err)
+		}
+	})
+}
+
+// addGoldenSeeds adds the named files of each golden case to the seed corpus
+func addGoldenSeeds(f *testing.F, names ...string) {
+	for _, name := range names {
+		files, err := filepath.Glob(filepath.Join("testdata", "golden", "*", name))
+		if err != nil {
+			f.Fatal(err)
+		}
+		for _, file := range files {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(data)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package booking
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+/*
+ * Guests' personal details, their names, contact details and the notes on
+ * their bookings, are kept for "retention_years" after they depart, then
+ * anonymised, along with what the bank said about their payments, which
+ * usually names them. The amounts and dates of the booking and its payments
+ * are kept for the accounts.
+ * Without a retention period, personal details are kept indefinitely.
+ *
+ * { "retention_years" : 6, "properties": [ ... ] }
+ */
+
+// ErrNoRetention is returned when redacting without a retention period
+var ErrNoRetention = errors.New("settings have no retention_years")
+
+// retentionCutoff returns the departure date before which guests' personal
+// details are no longer kept
+func (s Settings) retentionCutoff(now time.Time) (time.Time, error) {
+	if s.RetentionYears <= 0 {
+		return time.Time{}, ErrNoRetention
+	}
+	return s.Today(now).AddDate(-s.RetentionYears, 0, 0), nil
+}
+
+// hasPII reports whether a booking still holds personal details
+func (f FormInput) hasPII() bool {
+	return f.FirstName != "" || f.LastName != "" || f.Email != "" || f.Mobile != "" || f.Notes != ""
+}
+
+// anonymise removes the guest's personal details from a booking, including
+// the link to their other bookings
+func (f *FormInput) anonymise() {
+	f.FirstName = ""
+	f.LastName = ""
+	f.Email = ""
+	f.Mobile = ""
+	f.Notes = ""
+	f.GuestID = ""
+}
+
+// hasPII reports whether a booking, or what the bank said about its
+// payments, still holds personal details
+func (b Booking) hasPII() bool {
+	for _, r := range b.Receipts {
+		if r.Description != "" || !strings.HasPrefix(r.TransactionID, redactedPrefix) {
+			return true
+		}
+	}
+	return b.Form.hasPII()
+}
+
+// anonymise removes the guest's personal details from a booking and its
+// receipts. Transaction ids may be made from the bank's description, see
+// Transaction, so they are replaced by a digest, which still tells
+// Reconcile the transaction was recorded.
+func (b *Booking) anonymise() {
+	b.Form.anonymise()
+	receipts := append([]Receipt(nil), b.Receipts...)
+	for i := range receipts {
+		receipts[i].Description = ""
+		if !strings.HasPrefix(receipts[i].TransactionID, redactedPrefix) {
+			receipts[i].TransactionID = redactedID(receipts[i].TransactionID)
+		}
+	}
+	b.Receipts = receipts
+}
+
+// redactedPrefix begins the ids of transactions on anonymised receipts
+const redactedPrefix = "redacted:"
+
+// redactedID returns the digest an anonymised receipt keeps of its
+// transaction's id
+func redactedID(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return redactedPrefix + hex.EncodeToString(sum[:])
+}
+
+// RedactStore anonymises the stored bookings that departed before the
+// retention period, returning how many it anonymised
+func RedactStore(store BookingStore, settings Settings, now time.Time) (int, error) {
+	cutoff, err := settings.retentionCutoff(now)
+	if err != nil {
+		return 0, err
+	}
+	bookings, err := store.List(BookingFilter{To: cutoff})
+	if err != nil {
+		return 0, err
+	}
+	redacted := 0
+	for _, b := range bookings {
+		if !b.Departure.Before(cutoff) || !b.hasPII() {
+			continue
+		}
+		b.anonymise()
+		if err = store.Update(b); err != nil {
+			return redacted, err
+		}
+		redacted++
+	}
+	return redacted, nil
+}
+
+// RedactCSV copies a bookings CSV, anonymising the rows that departed before
+// the retention period, and returns how many it anonymised. Rows without a
+// departure date have it worked out from their booking reference.
+func RedactCSV(in io.Reader, out io.Writer, settings Settings, now time.Time) (int, error) {
+	cutoff, err := settings.retentionCutoff(now)
+	if err != nil {
+		return 0, err
+	}
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	w := csv.NewWriter(out)
+	redacted := 0
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return redacted, err
+		}
+		if row[0] != csvHeader[0] && len(row) > 6 {
+			var departure time.Time
+			if len(row) > 10 && row[10] != "" {
+				if departure, err = parseCSVDate(row[10]); err != nil {
+					return redacted, err
+				}
+			} else {
+				departure = CreateBooking(FormInput{BookingRef: row[0]}, settings).Departure
+			}
+			if departure.Before(cutoff) {
+				f := FormInput{FirstName: row[2], LastName: row[3], Email: row[4], Mobile: row[5], Notes: row[6]}
+				if f.hasPII() {
+					redacted++
+				}
+				for i := 2; i <= 6; i++ {
+					row[i] = ""
+				}
+				if len(row) > 37 {
+					row[37] = ""
+				}
+			}
+		}
+		w.Write(row)
+	}
+	w.Flush()
+	return redacted, w.Error()
+}
+
+// SubjectAccess returns the history of every guest who has booked with the
+// email address, for answering a subject access request
+func SubjectAccess(store BookingStore, email string) ([]GuestHistory, error) {
+	histories, err := GuestHistories(store)
+	if err != nil {
+		return nil, err
+	}
+	email = NormaliseEmail(email)
+	if email == "" {
+		return nil, nil
+	}
+	var found []GuestHistory
+	for _, h := range histories {
+		for _, b := range h.Bookings {
+			if NormaliseEmail(b.Form.Email) == email {
+				found = append(found, h)
+				break
+			}
+		}
+	}
+	return found, nil
+}
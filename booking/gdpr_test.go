@@ -0,0 +1,90 @@
+package booking
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactStore(t *testing.T) {
+	store := NewMemoryStore()
+	for _, b := range testBookings() {
+		b.Form.FirstName, b.Form.Email, b.Form.GuestID = "Ann", "ann@example.com", "ann"
+		b.Receipts = []Receipt{{Date: b.BookingDate, Amount: 100, TransactionID: "2017-05-21 100.00 ANN SMITH",
+			Description: "ANN SMITH"}}
+		store.Create(b)
+	}
+	// June's departure is more than a year ago, July's is not yet
+	now := Datetime(2018, time.July, 5)
+	if _, err := RedactStore(store, Settings{}, now); err != ErrNoRetention {
+		t.Errorf("RedactStore() error = %v, want %v", err, ErrNoRetention)
+	}
+	n, err := RedactStore(store, Settings{RetentionYears: 1}, now)
+	if err != nil || n != 1 {
+		t.Fatalf("RedactStore() = %v, %v, want 1", n, err)
+	}
+	if b, _ := store.Get("6FBJUN1719"); b.Form.hasPII() || b.Form.GuestID != "" || b.Net == 0 {
+		t.Errorf("RedactStore() left %+v", b.Form)
+	}
+	if b, _ := store.Get("6FBJUN1719"); strings.Contains(b.Receipts[0].TransactionID+b.Receipts[0].Description, "ANN") ||
+		b.Received() != 100 {
+		t.Errorf("RedactStore() left receipt %+v", b.Receipts[0])
+	}
+	// the redacted receipt's transaction is still known to be recorded
+	old := Transaction{ID: "2017-05-21 100.00 ANN SMITH", Date: Datetime(2017, time.May, 21), Amount: 100,
+		Description: "ANN SMITH 6FBJUN1719"}
+	if r, _ := Reconcile(store, []Transaction{old}, DefaultReconcileWindow); r.AlreadyRecorded != 1 {
+		t.Errorf("Reconcile() of a redacted receipt's transaction = %+v", r)
+	}
+	if b, _ := store.Get("6WWJUL0108"); b.Form.FirstName != "Ann" {
+		t.Errorf("RedactStore() redacted a booking within the retention period")
+	}
+	if n, _ = RedactStore(store, Settings{RetentionYears: 1}, now); n != 0 {
+		t.Errorf("RedactStore() again = %v, want 0", n)
+	}
+}
+
+func TestRedactCSV(t *testing.T) {
+	in := strings.Join(csvHeader, ",") + "\n" +
+		"6FBJUN1719,FooBarBaz,Ann,Smith,ann@example.com,07700 900123,late,2017-05-20,airbnb,2017-06-17,2017-06-19,2,400\n" +
+		"6WWJUL0108,WibbleWobbleWoo,Bob,Jones,bob@example.com,,,2017-05-21,email,,,4,900\n"
+	var out strings.Builder
+	n, err := RedactCSV(strings.NewReader(in), &out, Settings{RetentionYears: 1}, Datetime(2018, time.July, 5))
+	if err != nil || n != 1 {
+		t.Fatalf("RedactCSV() = %v, %v, want 1", n, err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if want := "6FBJUN1719,FooBarBaz,,,,,,2017-05-20,airbnb,2017-06-17,2017-06-19,2,400"; lines[1] != want {
+		t.Errorf("RedactCSV() row = %q, want %q", lines[1], want)
+	}
+	if !strings.Contains(lines[2], "Bob") {
+		t.Errorf("RedactCSV() redacted %q, whose departure is worked out from its reference", lines[2])
+	}
+}
+
+func TestSubjectAccess(t *testing.T) {
+	store := NewMemoryStore()
+	store.Create(guestBooking("6FBJUN0103", "ann@example.com", "07700 900123", 300))
+	store.Create(guestBooking("6FBAUG0102", "", "07700 900123", 200))
+	store.Create(guestBooking("6FBSEP0102", "bob@example.com", "", 100))
+	histories, err := SubjectAccess(store, " ANN@example.com")
+	if err != nil {
+		t.Fatalf("SubjectAccess() error = %v", err)
+	}
+	// the booking made by phone is Ann's too
+	if len(histories) != 1 || len(histories[0].Bookings) != 2 {
+		t.Errorf("SubjectAccess() = %+v, want Ann's 2 bookings", histories)
+	}
+}
+
+func TestWriteOwnerReport_omitPII(t *testing.T) {
+	r := OwnerReport{Property: testSettings.Properties[0], Bookings: testBookings()[:1], OmitPII: true}
+	r.Bookings[0].Form.FirstName = "Ann"
+	var out strings.Builder
+	if err := WriteOwnerReport(&out, r); err != nil {
+		t.Fatalf("WriteOwnerReport() error = %v", err)
+	}
+	if strings.Contains(out.String(), "Ann") || strings.Contains(out.String(), "guest") {
+		t.Errorf("WriteOwnerReport() = %q, want no guest names", out.String())
+	}
+}
@@ -0,0 +1,88 @@
+package booking
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// TestFixCSVGolden fixes each testdata/golden/<case>/bookings.csv with the
+// case's settings.json, and compares the out.csv WriteFixedCSV writes with
+// the case's want.csv. Run with -update after an intended change to the
+// output, and review the diff of the golden files.
+func TestFixCSVGolden(t *testing.T) {
+	cases, err := filepath.Glob(filepath.Join("testdata", "golden", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) == 0 {
+		t.Fatal("no golden cases in testdata/golden")
+	}
+	for _, dir := range cases {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			testFixCSVGolden(t, dir)
+		})
+	}
+}
+
+func testFixCSVGolden(t *testing.T, dir string) {
+	settingsJSON, err := ioutil.ReadFile(filepath.Join(dir, "settings.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, err := LoadSettings(settingsJSON)
+	if err != nil {
+		t.Fatalf("settings.json: %v", err)
+	}
+	spreadsheet := FixCSV(filepath.Join(dir, "bookings.csv"), settings)
+	// WriteFixedCSV always writes out.csv to the working directory
+	t.Chdir(t.TempDir())
+	WriteFixedCSV(spreadsheet)
+	got, err := ioutil.ReadFile("out.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(dir, "want.csv")
+	if *update {
+		if err := ioutil.WriteFile(want, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	wanted, err := ioutil.ReadFile(want)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, wanted) {
+		t.Errorf("out.csv differs from %s:\n%s", want, lineDiff(string(wanted), string(got)))
+	}
+}
+
+// lineDiff lists the lines of want and got that differ
+func lineDiff(want, got string) string {
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var diff bytes.Buffer
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			fmt.Fprintf(&diff, "line %d:\n- %s\n+ %s\n", i+1, w, g)
+		}
+	}
+	return diff.String()
+}
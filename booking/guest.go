@@ -0,0 +1,184 @@
+package booking
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+/*
+ * Guests are recorded on their bookings by FormInput.GuestID rather than
+ * stored by themselves. A new booking is linked to the guest of an earlier
+ * booking with the same email address or mobile number, once both are
+ * normalised; names alone are never enough to match. A new guest's ID is the
+ * reference of the first booking they are recorded with.
+ */
+
+// Guest is someone who has booked, with their contact details normalised
+type Guest struct {
+	ID        string
+	FirstName string
+	LastName  string
+	// Email is trimmed and lower case
+	Email string
+	// Mobile is in E.164 form, or empty if it could not be read
+	Mobile string
+}
+
+// GuestHistory is everything a guest has booked
+type GuestHistory struct {
+	Guest Guest
+	// Bookings are in order of arrival
+	Bookings []Booking
+	// Stays and Nights count the bookings that took place, or will
+	Stays  int
+	Nights int
+	// Spend is what the guest paid, less any discounts and refunds
+	Spend float64
+	// Properties are the short names of the properties booked, in the order first booked
+	Properties []string
+}
+
+// ErrGuestNotFound is returned for guests without a booking in the store
+var ErrGuestNotFound = errors.New("guest not found")
+
+// NormaliseEmail trims and lower-cases an email address
+func NormaliseEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// guestOf returns the guest who made a booking, whose mobile number is taken
+// to be in the property's country unless it has a country code, as
+// NormaliseContact does
+func guestOf(b Booking) Guest {
+	f := b.Form
+	mobile, err := NormalisePhone(f.Mobile, b.Property.country())
+	if err != nil {
+		mobile = ""
+	}
+	return Guest{
+		ID:        f.GuestID,
+		FirstName: strings.TrimSpace(f.FirstName),
+		LastName:  strings.TrimSpace(f.LastName),
+		Email:     NormaliseEmail(f.Email),
+		Mobile:    mobile,
+	}
+}
+
+// guestIndex finds guests by their contact details
+type guestIndex struct {
+	byEmail  map[string]string
+	byMobile map[string]string
+}
+
+// newGuestIndex indexes the guests of the linked bookings
+func newGuestIndex(bookings []Booking) *guestIndex {
+	ix := &guestIndex{byEmail: make(map[string]string), byMobile: make(map[string]string)}
+	for _, b := range bookings {
+		if b.Form.GuestID != "" {
+			ix.add(guestOf(b))
+		}
+	}
+	return ix
+}
+
+func (ix *guestIndex) add(g Guest) {
+	if _, ok := ix.byEmail[g.Email]; g.Email != "" && !ok {
+		ix.byEmail[g.Email] = g.ID
+	}
+	if _, ok := ix.byMobile[g.Mobile]; g.Mobile != "" && !ok {
+		ix.byMobile[g.Mobile] = g.ID
+	}
+}
+
+// link sets the booking's GuestID, if it has none, to the guest with the same
+// email address or mobile number, or else to a new guest
+func (ix *guestIndex) link(b *Booking) {
+	f := &b.Form
+	if f.GuestID == "" {
+		g := guestOf(*b)
+		if id, ok := ix.byEmail[g.Email]; ok && g.Email != "" {
+			f.GuestID = id
+		} else if id, ok := ix.byMobile[g.Mobile]; ok && g.Mobile != "" {
+			f.GuestID = id
+		} else {
+			f.GuestID = f.BookingRef
+		}
+	}
+	ix.add(guestOf(*b))
+}
+
+// LinkGuest links a new booking to the guests of the bookings in the store
+func LinkGuest(store BookingStore, b *Booking) error {
+	bookings, err := store.List(BookingFilter{})
+	if err != nil {
+		return err
+	}
+	newGuestIndex(bookings).link(b)
+	return nil
+}
+
+// GuestHistories returns the history of every guest with a booking in the
+// store, ordered by name. Bookings made before guests were recorded are
+// linked to guests as they would be now.
+func GuestHistories(store BookingStore) ([]GuestHistory, error) {
+	bookings, err := store.List(BookingFilter{})
+	if err != nil {
+		return nil, err
+	}
+	ix := newGuestIndex(bookings)
+	var histories []GuestHistory
+	index := make(map[string]int)
+	for _, b := range bookings {
+		ix.link(&b)
+		i, ok := index[b.Form.GuestID]
+		if !ok {
+			i = len(histories)
+			index[b.Form.GuestID] = i
+			histories = append(histories, GuestHistory{})
+		}
+		h := &histories[i]
+		// the latest booking has the guest's latest details
+		h.Guest = guestOf(b)
+		h.Bookings = append(h.Bookings, b)
+		h.Spend += b.Retained
+		if b.Form.Status.takesPlace() {
+			h.Stays++
+			h.Nights += NightsBetween(b.Arrival, b.Departure)
+		}
+		if !containsString(h.Properties, b.Property.ShortName) {
+			h.Properties = append(h.Properties, b.Property.ShortName)
+		}
+	}
+	sort.SliceStable(histories, func(i, j int) bool {
+		gi, gj := histories[i].Guest, histories[j].Guest
+		if gi.LastName != gj.LastName {
+			return gi.LastName < gj.LastName
+		}
+		return gi.FirstName < gj.FirstName
+	})
+	return histories, nil
+}
+
+// GuestHistoryFor returns the history of the guest with the given ID
+func GuestHistoryFor(store BookingStore, id string) (GuestHistory, error) {
+	histories, err := GuestHistories(store)
+	if err != nil {
+		return GuestHistory{}, err
+	}
+	for _, h := range histories {
+		if h.Guest.ID == id {
+			return h, nil
+		}
+	}
+	return GuestHistory{}, ErrGuestNotFound
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package booking
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func guestBooking(ref, email, mobile string, gross float64) Booking {
+	return CreateBooking(FormInput{BookingRef: ref, FirstName: "Ann", LastName: "Smith",
+		Email: email, Mobile: mobile, NumberOfPeople: 2, Gross: gross,
+		BookingDate: Datetime(2017, time.May, 1)}, testSettings)
+}
+
+func TestGuestHistories(t *testing.T) {
+	store := NewMemoryStore()
+	for _, b := range []Booking{
+		guestBooking("6FBJUN0103", "Ann@Example.com ", "07700 900123", 300),
+		// the same guest, by email
+		guestBooking("6WWJUL0105", "ann@example.com", "", 500),
+		// the same guest, by mobile
+		guestBooking("6FBAUG0102", "", "+44 7700 900123", 200),
+		// someone else with the same name
+		guestBooking("6FBSEP0102", "other@example.com", "", 100),
+	} {
+		if err := store.Create(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	histories, err := GuestHistories(store)
+	if err != nil {
+		t.Fatalf("GuestHistories() error = %v", err)
+	}
+	if len(histories) != 2 {
+		t.Fatalf("GuestHistories() = %d guests, want 2", len(histories))
+	}
+	h, err := GuestHistoryFor(store, "6FBJUN0103")
+	if err != nil {
+		t.Fatalf("GuestHistoryFor() error = %v", err)
+	}
+	if got := refs(h.Bookings); !reflect.DeepEqual(got, []string{"6FBJUN0103", "6WWJUL0105", "6FBAUG0102"}) {
+		t.Errorf("GuestHistoryFor() bookings = %v", got)
+	}
+	if h.Stays != 3 || h.Nights != 7 || h.Spend != 1000 {
+		t.Errorf("GuestHistoryFor() stays, nights, spend = %v, %v, %v, want 3, 7, 1000", h.Stays, h.Nights, h.Spend)
+	}
+	if !reflect.DeepEqual(h.Properties, []string{"FB", "WW"}) {
+		t.Errorf("GuestHistoryFor() properties = %v", h.Properties)
+	}
+	if h.Guest.Mobile != "+447700900123" {
+		t.Errorf("GuestHistoryFor() mobile = %v", h.Guest.Mobile)
+	}
+	if _, err := GuestHistoryFor(store, "nobody"); err != ErrGuestNotFound {
+		t.Errorf("GuestHistoryFor() error = %v, want %v", err, ErrGuestNotFound)
+	}
+}
+
+func TestGuestHistories_propertyCountry(t *testing.T) {
+	settings := testSettings
+	settings.Properties = append([]Property(nil), testSettings.Properties...)
+	settings.Properties[0].Country = "FR"
+	store := NewMemoryStore()
+	for _, f := range []FormInput{
+		{BookingRef: "6FBJUN0103", Mobile: "06 12 34 56 78"},
+		// the same guest, with the country code
+		{BookingRef: "6FBAUG0102", Mobile: "+33 6 12 34 56 78"},
+	} {
+		f.FirstName, f.NumberOfPeople, f.BookingDate = "Jean", 2, Datetime(2017, time.May, 1)
+		// stored as entered, as the booking form does
+		b := CreateBooking(f, settings)
+		if err := LinkGuest(store, &b); err != nil {
+			t.Fatal(err)
+		}
+		store.Create(b)
+	}
+	histories, err := GuestHistories(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(histories) != 1 || histories[0].Guest.Mobile != "+33612345678" {
+		t.Errorf("GuestHistories() = %+v, want one guest, in France", histories)
+	}
+}
+
+func TestImportCSV_linksGuests(t *testing.T) {
+	store := NewMemoryStore()
+	b := guestBooking("6FBJUN0103", "ann@example.com", "", 300)
+	b.Form.GuestID = "ann"
+	store.Create(b)
+
+	file := filepath.Join(t.TempDir(), "bookings.csv")
+	csv := "6WWJUL0105,WibbleWobbleWoo,Ann,Smith,ANN@example.com,,,2017-05-01,email,,,2,500\n" +
+		"6FBAUG0102,FooBarBaz,Bob,Jones,bob@example.com,,,2017-05-01,email,,,2,200\n" +
+		"6FBSEP0102,FooBarBaz,Bob,Jones,Bob@Example.com,,,2017-05-01,email,,,2,200\n"
+	if err := os.WriteFile(file, []byte(csv), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportCSV(file, testSettings, store); err != nil {
+		t.Fatalf("ImportCSV() error = %v", err)
+	}
+	for ref, want := range map[string]string{"6WWJUL0105": "ann", "6FBAUG0102": "6FBAUG0102", "6FBSEP0102": "6FBAUG0102"} {
+		if got, _ := store.Get(ref); got.Form.GuestID != want {
+			t.Errorf("ImportCSV() %s guest = %q, want %q", ref, got.Form.GuestID, want)
+		}
+	}
+}
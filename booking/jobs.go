@@ -0,0 +1,180 @@
+package booking
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The kinds of job done for a stay
+const (
+	GreetingJob = "greeting"
+	CleaningJob = "cleaning"
+	LaundryJob  = "laundry"
+)
+
+// Job is a visit to a property for a booking: greeting the guests when they
+// arrive, or cleaning and doing the laundry when they leave, or during longer
+// stays when the booking is charged for mid-stay services
+type Job struct {
+	Date time.Time
+	// Start and End are when the job is done: greetings start at check-in,
+	// and cleaning and laundry take from check-out to check-in
+	Start      time.Time
+	End        time.Time
+	Kind       string
+	Property   Property
+	BookingRef string
+	Guest      string
+	// People sizes the job: the guests to greet, or the beds to change
+	People int
+}
+
+// Summary describes the job in a line, e.g. "Greet 2 at FooBarBaz (6FBJUN1719)"
+func (j Job) Summary() string {
+	verb := map[string]string{GreetingJob: "Greet", CleaningJob: "Clean for", LaundryJob: "Laundry for"}[j.Kind]
+	return fmt.Sprintf("%s %d at %s (%s)", verb, j.People, j.Property.LongName, j.BookingRef)
+}
+
+// JobDay is the jobs to do on one day, across every property
+type JobDay struct {
+	Date time.Time
+	Jobs []Job
+}
+
+// bookingJobs returns the jobs for a booking whose stay takes place
+func bookingJobs(b Booking) []Job {
+	f := b.Form
+	if !f.Status.takesPlace() {
+		return nil
+	}
+	job := Job{Property: b.Property, BookingRef: f.BookingRef,
+		Guest: strings.TrimSpace(f.FirstName + " " + f.LastName), People: f.NumberOfPeople}
+	var jobs []Job
+	add := func(date time.Time, kind string) {
+		j := job
+		j.Date, j.Kind = date, kind
+		j.Start, j.End = b.Property.CheckOutOn(date), b.Property.CheckInOn(date)
+		if kind == GreetingJob {
+			j.Start, j.End = j.End, time.Time{}
+		}
+		jobs = append(jobs, j)
+	}
+	if f.IsGreeting {
+		add(b.Arrival, GreetingJob)
+	}
+	for _, item := range b.LineItems {
+		// the mid-stay services charged for, by whichever fee rule
+		kind := map[string]string{"cleaning": CleaningJob, "laundry": LaundryJob}[item.Service]
+		if item.Kind != MidStay || kind == "" {
+			continue
+		}
+		every := item.EveryNights
+		if every == 0 {
+			// bookings stored before line items said how often, spaced evenly
+			every = NightsBetween(b.Arrival, b.Departure) / (int(item.Base) + 1)
+		}
+		for k := 1; k <= int(item.Base); k++ {
+			add(b.Arrival.AddDate(0, 0, k*every), kind)
+		}
+	}
+	if f.IsCleaning {
+		add(b.Departure, CleaningJob)
+	}
+	if f.IsLaundry {
+		add(b.Departure, LaundryJob)
+	}
+	return jobs
+}
+
+// PlanJobs returns the jobs for the bookings on each day from from up to,
+// but not including, to, leaving out days without any jobs. A day's jobs are
+// ordered by property, then cleaning and laundry before greetings, as the
+// property is turned over between departing and arriving guests.
+func PlanJobs(bookings []Booking, from, to time.Time) []JobDay {
+	var jobs []Job
+	for _, b := range bookings {
+		for _, j := range bookingJobs(b) {
+			if !j.Date.Before(from) && j.Date.Before(to) {
+				jobs = append(jobs, j)
+			}
+		}
+	}
+	order := map[string]int{CleaningJob: 0, LaundryJob: 1, GreetingJob: 2}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		a, b := jobs[i], jobs[j]
+		switch {
+		case !a.Date.Equal(b.Date):
+			return a.Date.Before(b.Date)
+		case a.Property.ShortName != b.Property.ShortName:
+			return a.Property.ShortName < b.Property.ShortName
+		}
+		return order[a.Kind] < order[b.Kind]
+	})
+	var days []JobDay
+	for _, j := range jobs {
+		if len(days) == 0 || !days[len(days)-1].Date.Equal(j.Date) {
+			days = append(days, JobDay{Date: j.Date})
+		}
+		days[len(days)-1].Jobs = append(days[len(days)-1].Jobs, j)
+	}
+	return days
+}
+
+// WriteJobsCSV writes a job plan as CSV, header first
+func WriteJobsCSV(out io.Writer, days []JobDay) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"date", "job", "property", "booking_ref", "guest", "people"})
+	for _, d := range days {
+		for _, j := range d.Jobs {
+			w.Write([]string{d.Date.Format(DateLayout), j.Kind, j.Property.LongName,
+				j.BookingRef, j.Guest, fmt.Sprintf("%d", j.People)})
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// WriteJobsICS writes a job plan as an iCalendar. Jobs are events at their
+// times, in UTC so calendars show them at the right local time either side
+// of a change to or from summer time, or all day events if they have none.
+func WriteJobsICS(out io.Writer, days []JobDay) error {
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//supreme-garbanzo//jobs//EN"}
+	for _, d := range days {
+		for _, j := range d.Jobs {
+			date := d.Date.Format("20060102")
+			lines = append(lines,
+				"BEGIN:VEVENT",
+				fmt.Sprintf("UID:%s-%s-%s@%s", j.BookingRef, j.Kind, date, j.Property.ShortName),
+				"DTSTAMP:"+date+"T000000Z")
+			switch {
+			case j.Start.IsZero():
+				lines = append(lines, "DTSTART;VALUE=DATE:"+date,
+					"DTEND;VALUE=DATE:"+d.Date.AddDate(0, 0, 1).Format("20060102"))
+			case j.End.After(j.Start):
+				lines = append(lines, "DTSTART:"+icsTime(j.Start), "DTEND:"+icsTime(j.End))
+			default:
+				lines = append(lines, "DTSTART:"+icsTime(j.Start))
+			}
+			lines = append(lines,
+				"SUMMARY:"+icsEscape(j.Summary()),
+				"END:VEVENT")
+		}
+	}
+	lines = append(lines, "END:VCALENDAR")
+	_, err := io.WriteString(out, strings.Join(lines, "\r\n")+"\r\n")
+	return err
+}
+
+// icsTime writes a time in UTC for an iCalendar
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// icsEscape escapes text for an iCalendar property value
+func icsEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(text)
+}
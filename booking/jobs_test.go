@@ -0,0 +1,81 @@
+package booking
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanJobs(t *testing.T) {
+	settings := Settings{Properties: []Property{
+		{ShortName: "FB", LongName: "FooBarBaz", MidStayCleanEvery: 7},
+		{ShortName: "WW", LongName: "WibbleWobbleWoo", FeeRules: []FeeRuleConfig{
+			{Type: MidStay, Name: "daily_clean", Service: "cleaning", Amount: 10, EveryNights: 1}}},
+	}}
+	all := FormInput{NumberOfPeople: 2, IsGreeting: true, IsCleaning: true, IsLaundry: true}
+	bookings := []Booking{
+		CreateBooking(FormInput{BookingRef: "6FBJUN0110", NumberOfPeople: 3, IsGreeting: true, IsCleaning: true}, settings),
+		CreateBooking(func() FormInput { f := all; f.BookingRef = "6WWJUN0810"; return f }(), settings),
+		CreateBooking(func() FormInput { f := all; f.BookingRef = "6FBJUN1012"; return f }(), settings),
+		CreateBooking(func() FormInput { f := all; f.BookingRef = "6WWJUN1011"; f.Status = Cancelled; return f }(), settings),
+	}
+	days := PlanJobs(bookings, Datetime(2017, time.June, 2), Datetime(2017, time.June, 11))
+	var got []string
+	for _, d := range days {
+		var jobs []string
+		for _, j := range d.Jobs {
+			jobs = append(jobs, j.BookingRef[1:3]+" "+j.Kind)
+		}
+		got = append(got, d.Date.Format("02")+": "+strings.Join(jobs, ", "))
+	}
+	want := []string{
+		// the mid-stay clean a week into the stay
+		"08: FB cleaning, WW greeting",
+		// and the daily clean WW's fee rule charges for
+		"09: WW cleaning",
+		"10: FB cleaning, FB greeting, WW cleaning, WW laundry",
+	}
+	if strings.Join(got, "; ") != strings.Join(want, "; ") {
+		t.Errorf("PlanJobs() = %v, want %v", got, want)
+	}
+	if j := days[0].Jobs[0]; j.People != 3 {
+		t.Errorf("PlanJobs() people = %v, want 3", j.People)
+	}
+}
+
+func TestWriteJobs(t *testing.T) {
+	days := []JobDay{{Date: Datetime(2017, time.June, 17), Jobs: []Job{{Date: Datetime(2017, time.June, 17),
+		Kind: GreetingJob, Property: testSettings.Properties[0], BookingRef: "6FBJUN1719", Guest: "Ann Smith", People: 2}}}}
+	var csv, ics strings.Builder
+	if err := WriteJobsCSV(&csv, days); err != nil {
+		t.Fatalf("WriteJobsCSV() error = %v", err)
+	}
+	if want := "date,job,property,booking_ref,guest,people\n2017-06-17,greeting,FooBarBaz,6FBJUN1719,Ann Smith,2\n"; csv.String() != want {
+		t.Errorf("WriteJobsCSV() = %q, want %q", csv.String(), want)
+	}
+	if err := WriteJobsICS(&ics, days); err != nil {
+		t.Fatalf("WriteJobsICS() error = %v", err)
+	}
+	for _, line := range []string{"DTSTART;VALUE=DATE:20170617\r\n", "DTEND;VALUE=DATE:20170618\r\n",
+		"SUMMARY:Greet 2 at FooBarBaz (6FBJUN1719)\r\n", "END:VCALENDAR\r\n"} {
+		if !strings.Contains(ics.String(), line) {
+			t.Errorf("WriteJobsICS() = %q, want it to contain %q", ics.String(), line)
+		}
+	}
+}
+
+func TestWriteJobsICSTimes(t *testing.T) {
+	settings := Settings{Timezone: "Europe/London", Properties: testSettings.Properties}
+	b := CreateBooking(FormInput{BookingRef: "6FBJUN1719", NumberOfPeople: 2, IsGreeting: true, IsCleaning: true}, settings)
+	var ics strings.Builder
+	if err := WriteJobsICS(&ics, PlanJobs([]Booking{b}, Datetime(2017, time.June, 1), Datetime(2017, time.July, 1))); err != nil {
+		t.Fatalf("WriteJobsICS() error = %v", err)
+	}
+	// greeting at check-in, cleaning from check-out until check-in, in BST
+	for _, line := range []string{"DTSTART:20170617T150000Z\r\nSUMMARY:Greet",
+		"DTSTART:20170619T090000Z\r\nDTEND:20170619T150000Z\r\nSUMMARY:Clean"} {
+		if !strings.Contains(ics.String(), line) {
+			t.Errorf("WriteJobsICS() = %q, want it to contain %q", ics.String(), line)
+		}
+	}
+}
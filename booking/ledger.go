@@ -0,0 +1,427 @@
+package booking
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+/*
+ * The ledger is kept by double entry: every entry posts amounts to two or
+ * more accounts, debits positive and credits negative, which sum to zero.
+ * The accounts are
+ *
+ *   owner:<id>   what is owed to the house owner with id, a credit balance
+ *   guest_money  what guests paid, that is the owners'
+ *   bank         what has been paid out of the bank to owners
+ *   adjustments  what the agency has given owners, or taken back
+ *
+ * Owner income accrues when the guests depart, crediting the owner. If a
+ * booking is recalculated after that, e.g. for a late refund, the difference
+ * accrues the next time income is accrued, and if it is removed, what it
+ * accrued is reversed. Accruals are posted under the booking's original
+ * reference, which amending it does not change. Payouts debit the owner, and
+ * adjustments credit the owner or, when negative, debit them.
+ */
+
+// The kinds of ledger entry
+const (
+	AccrualEntry    = "accrual"
+	PayoutEntry     = "payout"
+	AdjustmentEntry = "adjustment"
+)
+
+// The accounts entries are posted to, besides the owners'
+const (
+	GuestMoneyAccount  = "guest_money"
+	BankAccount        = "bank"
+	AdjustmentsAccount = "adjustments"
+)
+
+// OwnerAccount returns the account of what is owed to a house owner
+func OwnerAccount(id string) string {
+	return "owner:" + id
+}
+
+// Posting debits an account with a positive amount, or credits it with a
+// negative one
+type Posting struct {
+	Account string  `json:"account"`
+	Amount  float64 `json:"amount"`
+}
+
+// LedgerEntry is a balanced set of postings
+type LedgerEntry struct {
+	Date       time.Time `json:"date"`
+	Kind       string    `json:"kind"`
+	Owner      string    `json:"owner"`
+	BookingRef string    `json:"booking_ref,omitempty"`
+	Memo       string    `json:"memo,omitempty"`
+	Postings   []Posting `json:"postings"`
+}
+
+// ErrUnbalanced is returned for entries whose postings do not sum to zero
+var ErrUnbalanced = errors.New("ledger entry does not balance")
+
+// pence rounds an amount to whole pence
+func pence(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// balanced checks an entry has postings, which sum to zero
+func (e LedgerEntry) balanced() error {
+	total := 0.0
+	for _, p := range e.Postings {
+		total += p.Amount
+	}
+	if len(e.Postings) < 2 || pence(total) != 0 {
+		return ErrUnbalanced
+	}
+	return nil
+}
+
+// amount returns what an entry posts to account
+func (e LedgerEntry) amount(account string) float64 {
+	total := 0.0
+	for _, p := range e.Postings {
+		if p.Account == account {
+			total += p.Amount
+		}
+	}
+	return total
+}
+
+// Ledger keeps ledger entries in the order they are posted. Entries are
+// never changed or removed; mistakes are put right by adjustments.
+type Ledger interface {
+	Post(e LedgerEntry) error
+	Entries() ([]LedgerEntry, error)
+}
+
+// MemoryLedger is a Ledger that lives only as long as the process
+type MemoryLedger struct {
+	mu      sync.RWMutex
+	entries []LedgerEntry
+}
+
+// NewMemoryLedger returns an empty ledger
+func NewMemoryLedger() *MemoryLedger {
+	return &MemoryLedger{}
+}
+
+// Post adds a balanced entry
+func (l *MemoryLedger) Post(e LedgerEntry) error {
+	if err := e.balanced(); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, e)
+	return nil
+}
+
+// Entries returns every entry, in the order they were posted
+func (l *MemoryLedger) Entries() ([]LedgerEntry, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]LedgerEntry(nil), l.entries...), nil
+}
+
+// FileLedger is a Ledger kept in a JSON-lines file, one entry per line. As
+// entries never change, each is appended to the file as it is posted.
+type FileLedger struct {
+	path   string
+	mu     sync.Mutex
+	ledger *MemoryLedger
+}
+
+// OpenFileLedger opens the ledger at path, which is created on first post if
+// it does not exist yet
+func OpenFileLedger(path string) (*FileLedger, error) {
+	l := &FileLedger{path: path, ledger: NewMemoryLedger()}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e LedgerEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, err
+		}
+		l.ledger.entries = append(l.ledger.entries, e)
+	}
+	return l, scanner.Err()
+}
+
+// Post adds a balanced entry, appending it to the file
+func (l *FileLedger) Post(e LedgerEntry) error {
+	if err := e.balanced(); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if err = json.NewEncoder(file).Encode(e); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return l.ledger.Post(e)
+}
+
+// Entries returns every entry, in the order they were posted
+func (l *FileLedger) Entries() ([]LedgerEntry, error) {
+	return l.ledger.Entries()
+}
+
+// AccrueOwnerIncome credits house owners with the owner income of the
+// bookings at their properties that departed on or before day, and returns
+// how many entries it posted. Enquiries accrue nothing, and bookings already
+// accrued only accrue any change in their owner income since. Accruals are
+// kept under the booking's original reference, so an amended booking, which
+// has a new one, is not accrued twice; and those of bookings that are no
+// longer in the store, or no longer accrue, e.g. as they were moved to later
+// dates, are reversed. Accruing to a day before an earlier accrual accrues as
+// far as that accrual did, so it reverses nothing that has not changed.
+func AccrueOwnerIncome(store BookingStore, settings Settings, ledger Ledger, day time.Time) (int, error) {
+	bookings, err := store.List(BookingFilter{})
+	if err != nil {
+		return 0, err
+	}
+	entries, err := ledger.Entries()
+	if err != nil {
+		return 0, err
+	}
+	// accrued is what each owner has accrued, by original reference
+	accrued := make(map[string]map[string]float64)
+	var refs []string
+	// through is the day bookings are accrued to
+	through := day
+	for _, e := range entries {
+		if e.Kind != AccrualEntry {
+			continue
+		}
+		if e.Date.After(through) {
+			through = e.Date
+		}
+		if accrued[e.BookingRef] == nil {
+			accrued[e.BookingRef] = make(map[string]float64)
+			refs = append(refs, e.BookingRef)
+		}
+		accrued[e.BookingRef][e.Owner] += e.amount(GuestMoneyAccount)
+	}
+	// due is what each owner should have accrued, by original reference
+	due := make(map[string]map[string]float64)
+	current := make(map[string]string)
+	for _, b := range bookings {
+		ref := originalRef(b)
+		current[ref] = b.Form.BookingRef
+		if due[ref] == nil {
+			due[ref] = make(map[string]float64)
+		}
+		if accrued[ref] == nil {
+			accrued[ref] = make(map[string]float64)
+			refs = append(refs, ref)
+		}
+		owner, ok := settings.OwnerOf(b.Property.ShortName)
+		if ok && !b.Departure.After(through) && b.Form.Status != Enquiry {
+			due[ref][owner.ID] += b.OwnerIncome
+		}
+	}
+	posted := 0
+	for _, ref := range refs {
+		var owners []string
+		for id := range accrued[ref] {
+			owners = append(owners, id)
+		}
+		for id := range due[ref] {
+			if _, ok := accrued[ref][id]; !ok {
+				owners = append(owners, id)
+			}
+		}
+		sort.Strings(owners)
+		for _, id := range owners {
+			before, ok := accrued[ref][id]
+			change := pence(due[ref][id] - before)
+			if change == 0 {
+				continue
+			}
+			memo := "owner income"
+			switch {
+			case current[ref] == "":
+				memo = "owner income reversed, booking removed"
+			case due[ref][id] == 0:
+				memo = "owner income reversed"
+			case ok:
+				memo = "owner income revised"
+			}
+			if current[ref] != "" && current[ref] != ref {
+				memo += ", now " + current[ref]
+			}
+			err = ledger.Post(LedgerEntry{Date: day, Kind: AccrualEntry, Owner: id, BookingRef: ref, Memo: memo,
+				Postings: []Posting{{GuestMoneyAccount, change}, {OwnerAccount(id), -change}}})
+			if err != nil {
+				return posted, err
+			}
+			posted++
+		}
+	}
+	return posted, nil
+}
+
+// originalRef returns the reference a booking was first made under, before
+// any amendments
+func originalRef(b Booking) string {
+	if len(b.Amendments) > 0 {
+		return b.Amendments[0].PreviousRef
+	}
+	return b.Form.BookingRef
+}
+
+// PostAdjustment credits a house owner with amount, or debits them if it is
+// negative, e.g. for repairs the agency paid for
+func PostAdjustment(ledger Ledger, owner HouseOwner, amount float64, memo string, day time.Time) error {
+	amount = pence(amount)
+	if amount == 0 {
+		return errors.New("an adjustment needs an amount")
+	}
+	return ledger.Post(LedgerEntry{Date: day, Kind: AdjustmentEntry, Owner: owner.ID, Memo: memo,
+		Postings: []Posting{{AdjustmentsAccount, amount}, {OwnerAccount(owner.ID), -amount}}})
+}
+
+// OwnerBalance is what has been accrued, adjusted and paid out to a house
+// owner, and what is still owed to them
+type OwnerBalance struct {
+	Owner       HouseOwner
+	Accrued     float64
+	Adjusted    float64
+	Paid        float64
+	Outstanding float64
+}
+
+// OwnerBalances returns the balance of every house owner, from the entries
+// dated on or before day
+func OwnerBalances(ledger Ledger, settings Settings, day time.Time) ([]OwnerBalance, error) {
+	entries, err := ledger.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var balances []OwnerBalance
+	for _, o := range settings.Owners {
+		b := OwnerBalance{Owner: o}
+		account := OwnerAccount(o.ID)
+		for _, e := range entries {
+			if e.Date.After(day) {
+				continue
+			}
+			// credits to the owner are negative
+			amount := -e.amount(account)
+			switch e.Kind {
+			case AccrualEntry:
+				b.Accrued += amount
+			case AdjustmentEntry:
+				b.Adjusted += amount
+			case PayoutEntry:
+				b.Paid -= amount
+			}
+			b.Outstanding += amount
+		}
+		b.Accrued, b.Adjusted, b.Paid, b.Outstanding = pence(b.Accrued), pence(b.Adjusted), pence(b.Paid), pence(b.Outstanding)
+		balances = append(balances, b)
+	}
+	sort.SliceStable(balances, func(i, j int) bool { return balances[i].Owner.ID < balances[j].Owner.ID })
+	return balances, nil
+}
+
+// WriteOwnerBalances writes balances as an aligned plain text table
+func WriteOwnerBalances(out io.Writer, balances []OwnerBalance, day time.Time) error {
+	fmt.Fprintf(out, "House owner balances on %s\n\n", day.Format(DateLayout))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "owner\tname\taccrued\tadjusted\tpaid\toutstanding\t")
+	var total OwnerBalance
+	for _, b := range balances {
+		fmt.Fprintf(w, "%s\t%s\t%.2f\t%.2f\t%.2f\t%.2f\t\n", b.Owner.ID, b.Owner.Name,
+			b.Accrued, b.Adjusted, b.Paid, b.Outstanding)
+		total.Accrued += b.Accrued
+		total.Adjusted += b.Adjusted
+		total.Paid += b.Paid
+		total.Outstanding += b.Outstanding
+	}
+	fmt.Fprintf(w, "total\t\t%.2f\t%.2f\t%.2f\t%.2f\t\n", total.Accrued, total.Adjusted, total.Paid, total.Outstanding)
+	return w.Flush()
+}
+
+// Payout is a payment to a house owner of what they are owed
+type Payout struct {
+	Date   time.Time
+	Owner  HouseOwner
+	Amount float64
+	// Reference is given with the payment, e.g. "jones 2017-07-01"
+	Reference string
+}
+
+// PlanPayouts returns a payout for every house owner whose payout is due on
+// day, of what they are owed, unless it is less than their minimum
+func PlanPayouts(ledger Ledger, settings Settings, day time.Time) ([]Payout, error) {
+	balances, err := OwnerBalances(ledger, settings, day)
+	if err != nil {
+		return nil, err
+	}
+	var payouts []Payout
+	for _, b := range balances {
+		if !b.Owner.Payout.due(day) || b.Outstanding <= 0 || b.Outstanding < b.Owner.Payout.Minimum {
+			continue
+		}
+		payouts = append(payouts, Payout{Date: day, Owner: b.Owner, Amount: b.Outstanding,
+			Reference: b.Owner.ID + " " + day.Format(DateLayout)})
+	}
+	return payouts, nil
+}
+
+// RecordPayouts posts payouts to the ledger, once they are made
+func RecordPayouts(ledger Ledger, payouts []Payout) error {
+	for _, p := range payouts {
+		err := ledger.Post(LedgerEntry{Date: p.Date, Kind: PayoutEntry, Owner: p.Owner.ID, Memo: p.Reference,
+			Postings: []Posting{{OwnerAccount(p.Owner.ID), p.Amount}, {BankAccount, -p.Amount}}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WritePayoutsCSV writes payouts as CSV, header first, for making them at
+// the bank
+func WritePayoutsCSV(out io.Writer, payouts []Payout) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"date", "owner", "name", "bank_reference", "amount", "reference"})
+	for _, p := range payouts {
+		w.Write([]string{p.Date.Format(DateLayout), p.Owner.ID, p.Owner.Name, p.Owner.BankReference,
+			fmt.Sprintf("%.2f", p.Amount), p.Reference})
+	}
+	w.Flush()
+	return w.Error()
+}
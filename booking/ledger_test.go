@@ -0,0 +1,193 @@
+package booking
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func ownerIncome(bookings []Booking, property string, before time.Time) float64 {
+	total := 0.0
+	for _, b := range bookings {
+		if b.Property.ShortName == property && !b.Departure.After(before) {
+			total += b.OwnerIncome
+		}
+	}
+	return pence(total)
+}
+
+func TestAccrueOwnerIncome(t *testing.T) {
+	settings := ownerSettings()
+	store := NewMemoryStore()
+	for _, b := range testBookings() {
+		store.Create(b)
+	}
+	ledger := NewMemoryLedger()
+	day := Datetime(2017, time.July, 31)
+	n, err := AccrueOwnerIncome(store, settings, ledger, day)
+	if err != nil || n != 2 {
+		t.Fatalf("AccrueOwnerIncome() = %v, %v, want 2 accruals", n, err)
+	}
+	if n, _ = AccrueOwnerIncome(store, settings, ledger, day); n != 0 {
+		t.Errorf("AccrueOwnerIncome() again = %v, want 0", n)
+	}
+
+	// a refund after departure accrues the difference
+	b, _ := store.Get("6FBJUN1719")
+	b.OwnerIncome -= 20
+	store.Update(b)
+	if n, _ = AccrueOwnerIncome(store, settings, ledger, day); n != 1 {
+		t.Errorf("AccrueOwnerIncome() revised = %v, want 1", n)
+	}
+	bookings, _ := store.List(BookingFilter{})
+	balances, err := OwnerBalances(ledger, settings, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []float64{ownerIncome(bookings, "FB", day), ownerIncome(bookings, "WW", day)} {
+		if balances[i].Accrued != want || balances[i].Outstanding != want {
+			t.Errorf("%s balance = %+v, want %.2f accrued", balances[i].Owner.ID, balances[i], want)
+		}
+	}
+}
+
+func TestAccrueOwnerIncome_amended(t *testing.T) {
+	settings := ownerSettings()
+	store := NewMemoryStore()
+	for _, b := range testBookings() {
+		store.Create(b)
+	}
+	ledger := NewMemoryLedger()
+	day := Datetime(2017, time.July, 31)
+	AccrueOwnerIncome(store, settings, ledger, day)
+
+	// a night longer, under a new reference
+	amended, err := AmendBooking(store, settings, "6FBJUN1719", Datetime(2017, time.June, 17),
+		Datetime(2017, time.June, 20), Datetime(2017, time.June, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	AccrueOwnerIncome(store, settings, ledger, day)
+	balances, _ := OwnerBalances(ledger, settings, day)
+	if want := pence(amended.OwnerIncome); balances[0].Accrued != want {
+		t.Errorf("jones accrued %.2f after the amendment, want %.2f", balances[0].Accrued, want)
+	}
+	entries, _ := ledger.Entries()
+	for _, e := range entries {
+		if e.BookingRef == amended.Form.BookingRef {
+			t.Errorf("accrued under the new reference: %+v", e)
+		}
+	}
+
+	// removed bookings have their accruals reversed
+	store.Delete(amended.Form.BookingRef)
+	if n, _ := AccrueOwnerIncome(store, settings, ledger, day); n != 1 {
+		t.Errorf("AccrueOwnerIncome() after removing a booking = %v, want 1 reversal", n)
+	}
+	balances, _ = OwnerBalances(ledger, settings, day)
+	if balances[0].Accrued != 0 {
+		t.Errorf("jones accrued %.2f after the booking was removed, want 0", balances[0].Accrued)
+	}
+	if n, _ := AccrueOwnerIncome(store, settings, ledger, day); n != 0 {
+		t.Errorf("AccrueOwnerIncome() again = %v, want 0", n)
+	}
+}
+
+func TestAccrueOwnerIncome_earlierDay(t *testing.T) {
+	settings := ownerSettings()
+	store := NewMemoryStore()
+	for _, b := range testBookings() {
+		store.Create(b)
+	}
+	ledger := NewMemoryLedger()
+	if n, _ := AccrueOwnerIncome(store, settings, ledger, Datetime(2017, time.July, 31)); n != 2 {
+		t.Fatalf("AccrueOwnerIncome() = %v, want 2", n)
+	}
+	// 6WWJUL0108 departs after this day, but has been accrued and not changed
+	earlier := Datetime(2017, time.June, 30)
+	if n, _ := AccrueOwnerIncome(store, settings, ledger, earlier); n != 0 {
+		entries, _ := ledger.Entries()
+		t.Errorf("AccrueOwnerIncome() on an earlier day = %v, want 0: %+v", n, entries[2:])
+	}
+
+	// a booking moved to after the last accrual is still reversed
+	if _, err := AmendBooking(store, settings, "6FBJUN1719", Datetime(2017, time.August, 10),
+		Datetime(2017, time.August, 12), earlier); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := AccrueOwnerIncome(store, settings, ledger, earlier); n != 1 {
+		t.Errorf("AccrueOwnerIncome() after moving a booking later = %v, want 1 reversal", n)
+	}
+}
+
+func TestPlanPayouts(t *testing.T) {
+	settings := ownerSettings()
+	ledger := NewMemoryLedger()
+	jones, _ := settings.Owner("jones")
+	smith, _ := settings.Owner("smith")
+	first := Datetime(2017, time.August, 1)
+	PostAdjustment(ledger, jones, 40, "opening balance", Datetime(2017, time.July, 1))
+	PostAdjustment(ledger, smith, 120.5, "opening balance", Datetime(2017, time.July, 1))
+	PostAdjustment(ledger, smith, -20.5, "broken window", Datetime(2017, time.July, 20))
+
+	// jones is owed less than their minimum
+	payouts, err := PlanPayouts(ledger, settings, first)
+	if err != nil || len(payouts) != 1 || payouts[0].Owner.ID != "smith" || payouts[0].Amount != 100 {
+		t.Fatalf("PlanPayouts() = %+v, %v", payouts, err)
+	}
+	var buf bytes.Buffer
+	if err := WritePayoutsCSV(&buf, payouts); err != nil {
+		t.Fatal(err)
+	}
+	want := "date,owner,name,bank_reference,amount,reference\n" +
+		"2017-08-01,smith,Ms Smith,40-00-00 87654321,100.00,smith 2017-08-01\n"
+	if buf.String() != want {
+		t.Errorf("WritePayoutsCSV() =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	if err := RecordPayouts(ledger, payouts); err != nil {
+		t.Fatal(err)
+	}
+	if payouts, _ = PlanPayouts(ledger, settings, first); len(payouts) != 0 {
+		t.Errorf("PlanPayouts() after paying = %+v", payouts)
+	}
+	balances, _ := OwnerBalances(ledger, settings, first)
+	if b := balances[1]; b.Adjusted != 100 || b.Paid != 100 || b.Outstanding != 0 {
+		t.Errorf("smith balance = %+v", b)
+	}
+	buf.Reset()
+	WriteOwnerBalances(&buf, balances, first)
+	if !strings.Contains(buf.String(), "40.00") {
+		t.Errorf("WriteOwnerBalances() does not show jones is owed 40.00:\n%s", buf.String())
+	}
+}
+
+func TestLedger_unbalanced(t *testing.T) {
+	ledger := NewMemoryLedger()
+	err := ledger.Post(LedgerEntry{Kind: AdjustmentEntry, Postings: []Posting{{BankAccount, 10}, {OwnerAccount("jones"), -9}}})
+	if err != ErrUnbalanced {
+		t.Errorf("Post() error = %v, want %v", err, ErrUnbalanced)
+	}
+}
+
+func TestFileLedger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	ledger, err := OpenFileLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jones := HouseOwner{ID: "jones"}
+	if err := PostAdjustment(ledger, jones, 12.34, "opening balance", Datetime(2017, time.July, 1)); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := OpenFileLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := reopened.Entries()
+	if len(entries) != 1 || entries[0].amount(OwnerAccount("jones")) != -12.34 || entries[0].Memo != "opening balance" {
+		t.Errorf("reopened ledger entries = %+v", entries)
+	}
+}
@@ -0,0 +1,159 @@
+package booking
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// Status is where a booking is in its lifecycle
+type Status int
+
+// Confirmed and others are the statuses a booking can have. The zero Status is
+// Confirmed, so bookings recorded before statuses existed stay confirmed.
+const (
+	Confirmed Status = iota
+	Enquiry
+	Cancelled
+	NoShow
+	Completed
+	nStatuses = int(Completed) + 1
+)
+
+var statusNames = [nStatuses]string{"confirmed", "enquiry", "cancelled", "no-show", "completed"}
+
+func (s Status) String() string {
+	if s < 0 || int(s) >= nStatuses {
+		return ""
+	}
+	return statusNames[s]
+}
+
+// ParseStatus returns the Status with the given name, as written in the CSV.
+// An empty name is Confirmed.
+func ParseStatus(name string) (Status, bool) {
+	if name == "" {
+		return Confirmed, true
+	}
+	for x := Confirmed; int(x) < nStatuses; x++ {
+		if x.String() == name {
+			return x, true
+		}
+	}
+	return Confirmed, false
+}
+
+// takesPlace reports whether the guest actually stays on a booking with this status
+func (s Status) takesPlace() bool {
+	return s != Cancelled && s != NoShow
+}
+
+// CancellationTier refunds a fraction of the gross to guests who cancel at
+// least DaysBeforeArrival days before they were due to arrive
+type CancellationTier struct {
+	DaysBeforeArrival int     `json:"days_before_arrival"`
+	Refund            float64 `json:"refund"`
+}
+
+// RefundFraction returns the fraction of the gross refunded for a
+// cancellation made on cancelled. It is the refund of the tier with the
+// most notice that the cancellation still meets; with no such tier nothing
+// is refunded.
+func (p Property) RefundFraction(cancelled, arrival time.Time) float64 {
+	tiers := append([]CancellationTier(nil), p.CancellationPolicy...)
+	sort.Slice(tiers, func(i, j int) bool {
+		return tiers[i].DaysBeforeArrival > tiers[j].DaysBeforeArrival
+	})
+	notice := int(arrival.Sub(cancelled).Hours() / 24)
+	for _, t := range tiers {
+		if notice >= t.DaysBeforeArrival {
+			return t.Refund
+		}
+	}
+	return 0
+}
+
+// Amendment records a change of dates on a booking, and the reference it had before
+type Amendment struct {
+	Date              time.Time
+	PreviousRef       string
+	PreviousArrival   time.Time
+	PreviousDeparture time.Time
+}
+
+// ErrUnencodableDates is returned for stays a booking reference cannot describe
+var ErrUnencodableDates = errors.New("these dates cannot be expressed as a booking reference")
+
+// BookingRefFor generates the booking reference for a stay. The reference only
+// holds the year of booking and the day of departure, so it is checked to
+// decode back to the same dates.
+func BookingRefFor(property Property, arrival, departure, bookingDate time.Time, settings Settings) (string, error) {
+	ref := CreateBookingRef(Booking{
+		Property:    property,
+		Arrival:     arrival,
+		Departure:   departure,
+		BookingDate: bookingDate,
+	}, settings)
+	b := CreateBooking(FormInput{BookingRef: ref, NumberOfPeople: 1}, settings)
+	if !b.Arrival.Equal(arrival) || !b.Departure.Equal(departure) {
+		return "", ErrUnencodableDates
+	}
+	return ref, nil
+}
+
+// Recalculate works out a booking again from its form, keeping its history
+func Recalculate(b Booking, settings Settings) Booking {
+	nb := CreateBooking(b.Form, settings)
+	nb.Amendments = b.Amendments
+	nb.Receipts = b.Receipts
+	return nb
+}
+
+// AmendBooking moves a stored booking to new dates. This gives it a new
+// booking reference, so it is stored under the new reference and the old
+// one is recorded in its amendment history.
+func AmendBooking(store BookingStore, settings Settings, ref string, arrival, departure, on time.Time) (Booking, error) {
+	b, err := store.Get(ref)
+	if err != nil {
+		return b, err
+	}
+	if !departure.After(arrival) {
+		return b, errors.New("departure must be after arrival")
+	}
+	newRef, err := BookingRefFor(b.Property, arrival, departure, b.BookingDate, settings)
+	if err != nil {
+		return b, err
+	}
+	amended := b
+	amended.Form.BookingRef = newRef
+	amended.Amendments = append(append([]Amendment(nil), b.Amendments...), Amendment{
+		Date:              on,
+		PreviousRef:       ref,
+		PreviousArrival:   b.Arrival,
+		PreviousDeparture: b.Departure,
+	})
+	amended = Recalculate(amended, settings)
+	if newRef == ref {
+		return amended, store.Update(amended)
+	}
+	if err = store.Create(amended); err != nil {
+		return b, err
+	}
+	return amended, store.Delete(ref)
+}
+
+// SetBookingStatus moves a stored booking to a new status, recalculating its
+// fees. For a cancellation, on is the date the guest cancelled.
+func SetBookingStatus(store BookingStore, settings Settings, ref string, status Status, on time.Time) (Booking, error) {
+	b, err := store.Get(ref)
+	if err != nil {
+		return b, err
+	}
+	b.Form.Status = status
+	b.Form.CancellationDate = time.Time{}
+	if status == Cancelled {
+		b.Form.CancellationDate = on
+	}
+	b = Recalculate(b, settings)
+	return b, store.Update(b)
+}
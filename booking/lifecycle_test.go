@@ -0,0 +1,133 @@
+package booking
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var policySettings = Settings{Properties: []Property{
+	{LongName: "FooBarBaz", ShortName: "FB", HouseOwnerCommission: 0.1,
+		Greeting: 15, Laundry: []float64{10, 10, 15, 15, 25, 25}, Cleaning: 35,
+		Consumables: []float64{15, 15, 25, 25, 35, 35},
+		CancellationPolicy: []CancellationTier{
+			{DaysBeforeArrival: 7, Refund: 0.5},
+			{DaysBeforeArrival: 30, Refund: 1},
+		}},
+}}
+
+func TestProperty_refundFraction(t *testing.T) {
+	arrival := Datetime(2017, time.June, 17)
+	tests := []struct {
+		name      string
+		cancelled time.Time
+		want      float64
+	}{
+		{"months ahead", Datetime(2017, time.January, 1), 1},
+		{"exactly 30 days", Datetime(2017, time.May, 18), 1},
+		{"29 days", Datetime(2017, time.May, 19), 0.5},
+		{"exactly 7 days", Datetime(2017, time.June, 10), 0.5},
+		{"last minute", Datetime(2017, time.June, 16), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policySettings.Properties[0].RefundFraction(tt.cancelled, arrival); got != tt.want {
+				t.Errorf("RefundFraction() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_createBooking_status(t *testing.T) {
+	form := FormInput{BookingRef: "6FBJUN1719", Source: Email, NumberOfPeople: 2, Gross: 400,
+		IsGreeting: true, IsLaundry: true, IsCleaning: true, IsConsumables: true}
+	tests := []struct {
+		name         string
+		status       Status
+		cancelled    time.Time
+		wantRefund   float64
+		wantFees     float64
+		wantHouseFee float64
+	}{
+		// 15 + 10 + 35 + 15 services, plus 10% house owner fee
+		{"confirmed", Confirmed, time.Time{}, 0, 115, 40},
+		{"half refund", Cancelled, Datetime(2017, time.June, 1), 200, 20, 20},
+		{"full refund", Cancelled, Datetime(2017, time.January, 1), 400, 0, 0},
+		{"no refund", Cancelled, Datetime(2017, time.June, 16), 0, 40, 40},
+		{"no-show", NoShow, time.Time{}, 0, 40, 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := form
+			f.Status = tt.status
+			f.CancellationDate = tt.cancelled
+			b := CreateBooking(f, policySettings)
+			if b.Refund != tt.wantRefund {
+				t.Errorf("CreateBooking() refund = %v, want %v", b.Refund, tt.wantRefund)
+			}
+			if b.Retained != f.Gross-tt.wantRefund {
+				t.Errorf("CreateBooking() retained = %v, want %v", b.Retained, f.Gross-tt.wantRefund)
+			}
+			if math.Abs(b.TotalFees-tt.wantFees) > 1e-9 {
+				t.Errorf("CreateBooking() total fees = %v, want %v", b.TotalFees, tt.wantFees)
+			}
+			if math.Abs(b.HouseOwnerFee-tt.wantHouseFee) > 1e-9 {
+				t.Errorf("CreateBooking() house owner fee = %v, want %v", b.HouseOwnerFee, tt.wantHouseFee)
+			}
+		})
+	}
+}
+
+func TestAmendBooking(t *testing.T) {
+	store := NewMemoryStore()
+	store.Create(CreateBooking(FormInput{BookingRef: "6FBJUN1719", NumberOfPeople: 2, Gross: 400,
+		BookingDate: Datetime(2017, time.May, 20)}, policySettings))
+	on := Datetime(2017, time.May, 25)
+
+	b, err := AmendBooking(store, policySettings, "6FBJUN1719",
+		Datetime(2017, time.June, 18), Datetime(2017, time.June, 21), on)
+	if err != nil {
+		t.Fatalf("AmendBooking() error = %v", err)
+	}
+	if b.Form.BookingRef != "6FBJUN1821" {
+		t.Errorf("AmendBooking() ref = %v, want 6FBJUN1821", b.Form.BookingRef)
+	}
+	if _, err := store.Get("6FBJUN1719"); err != ErrBookingNotFound {
+		t.Errorf("old reference still stored, error = %v", err)
+	}
+	stored, err := store.Get("6FBJUN1821")
+	if err != nil {
+		t.Fatalf("amended booking not stored: %v", err)
+	}
+	want := Amendment{Date: on, PreviousRef: "6FBJUN1719",
+		PreviousArrival: Datetime(2017, time.June, 17), PreviousDeparture: Datetime(2017, time.June, 19)}
+	if len(stored.Amendments) != 1 || stored.Amendments[0] != want {
+		t.Errorf("amendments = %+v, want [%+v]", stored.Amendments, want)
+	}
+
+	if _, err := AmendBooking(store, policySettings, "6FBJUN1821",
+		Datetime(2018, time.January, 2), Datetime(2018, time.January, 5), on); err != ErrUnencodableDates {
+		t.Errorf("AmendBooking() error = %v, want %v", err, ErrUnencodableDates)
+	}
+}
+
+func TestSetBookingStatus(t *testing.T) {
+	store := NewMemoryStore()
+	store.Create(CreateBooking(FormInput{BookingRef: "6FBJUN1719", NumberOfPeople: 2, Gross: 400,
+		BookingDate: Datetime(2017, time.May, 20)}, policySettings))
+
+	b, err := SetBookingStatus(store, policySettings, "6FBJUN1719", Cancelled, Datetime(2017, time.June, 1))
+	if err != nil {
+		t.Fatalf("SetBookingStatus() error = %v", err)
+	}
+	if b.Refund != 200 {
+		t.Errorf("SetBookingStatus() refund = %v, want 200", b.Refund)
+	}
+	b, _ = SetBookingStatus(store, policySettings, "6FBJUN1719", Confirmed, time.Time{})
+	if b.Refund != 0 || !b.Form.CancellationDate.IsZero() {
+		t.Errorf("reinstated booking refund = %v, cancellation date = %v", b.Refund, b.Form.CancellationDate)
+	}
+	if _, err := SetBookingStatus(store, policySettings, "6FBJUL0108", Cancelled, time.Time{}); err != ErrBookingNotFound {
+		t.Errorf("SetBookingStatus() error = %v, want %v", err, ErrBookingNotFound)
+	}
+}
@@ -0,0 +1,251 @@
+package booking
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+/*
+ * Guests are emailed a confirmation when they book, a reminder before they
+ * arrive and a thank-you after they leave. Each email is a text/template
+ * rendered with the Booking, whose first line is the subject:
+ *
+ *   Subject: Your stay at {{.Property.LongName}}
+ *
+ *   Dear {{.Form.FirstName}}, ...
+ *
+ * A property's templates are the files confirmation.tmpl, pre_arrival.tmpl
+ * and thank_you.tmpl in its "template_dir". Any it lacks are the defaults
+ * below.
+ */
+
+// The kinds of email sent to guests
+const (
+	ConfirmationEmail = "confirmation"
+	PreArrivalEmail   = "pre_arrival"
+	ThankYouEmail     = "thank_you"
+)
+
+var emailKinds = []string{ConfirmationEmail, PreArrivalEmail, ThankYouEmail}
+
+var defaultEmailTemplates = map[string]string{
+	ConfirmationEmail: `Subject: Your booking at {{.Property.LongName}} ({{.Form.BookingRef}})
+
+Dear {{.Form.FirstName}},
+
+Thank you for booking {{.Property.LongName}} from {{date .Arrival}} to {{date .Departure}}.
+Your booking reference is {{.Form.BookingRef}}.
+`,
+	PreArrivalEmail: `Subject: See you soon at {{.Property.LongName}}
+
+Dear {{.Form.FirstName}},
+
+We look forward to welcoming you to {{.Property.LongName}} on {{date .Arrival}}.
+`,
+	ThankYouEmail: `Subject: Thank you for staying at {{.Property.LongName}}
+
+Dear {{.Form.FirstName}},
+
+Thank you for staying with us. We hope to see you again.
+`,
+}
+
+var emailFuncs = template.FuncMap{
+	"date":  func(t time.Time) string { return t.Format("Monday 2 January 2006") },
+	"money": func(amount float64) string { return fmt.Sprintf("%.2f", amount) },
+}
+
+// Message is an email to a guest about a booking
+type Message struct {
+	From       string
+	To         string
+	Subject    string
+	Body       string
+	BookingRef string
+	Kind       string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(m Message) error
+}
+
+// EMLMailer "sends" emails by writing them to .eml files in Dir, for sending
+// or checking by hand. Files are named after the booking, kind of email and
+// when it was sent, e.g. 6FBJUN1719-confirmation-20170501-090000.eml, so an
+// email sent again, e.g. after the booking was amended, never replaces one
+// sent before.
+type EMLMailer struct {
+	Dir string
+	// Now is when the emails are dated, or time.Now if nil
+	Now Clock
+}
+
+// Send writes the email to Dir
+func (m EMLMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0755); err != nil {
+		return err
+	}
+	now := time.Now
+	if m.Now != nil {
+		now = m.Now
+	}
+	var eml bytes.Buffer
+	fmt.Fprintf(&eml, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&eml, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&eml, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&eml, "Date: %s\r\n", now().Format(time.RFC1123Z))
+	eml.WriteString("MIME-Version: 1.0\r\n")
+	eml.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	eml.WriteString(strings.Replace(msg.Body, "\n", "\r\n", -1))
+	base := fmt.Sprintf("%s-%s-%s", msg.BookingRef, msg.Kind, now().Format("20060102-150405"))
+	for n := 1; ; n++ {
+		name := base + ".eml"
+		if n > 1 {
+			// more than one in the same second
+			name = fmt.Sprintf("%s-%d.eml", base, n)
+		}
+		file, err := os.OpenFile(filepath.Join(m.Dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, err = file.Write(eml.Bytes()); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	}
+}
+
+// ErrNoSubject is returned for email templates without a subject line
+var ErrNoSubject = errors.New(`email template must start with "Subject: "`)
+
+// emailTemplate returns the text of the property's template for a kind of email
+func (p Property) emailTemplate(kind string) (string, error) {
+	if p.TemplateDir != "" {
+		text, err := ioutil.ReadFile(filepath.Join(p.TemplateDir, kind+".tmpl"))
+		if err == nil {
+			return string(text), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	text, ok := defaultEmailTemplates[kind]
+	if !ok {
+		return "", fmt.Errorf("unknown kind of email %q", kind)
+	}
+	return text, nil
+}
+
+// RenderEmail renders a kind of email to the guest of a booking
+func RenderEmail(b Booking, kind, from string) (Message, error) {
+	text, err := b.Property.emailTemplate(kind)
+	if err != nil {
+		return Message{}, err
+	}
+	t, err := template.New(kind).Funcs(emailFuncs).Parse(text)
+	if err != nil {
+		return Message{}, err
+	}
+	var out bytes.Buffer
+	if err = t.Execute(&out, b); err != nil {
+		return Message{}, err
+	}
+	parts := strings.SplitN(out.String(), "\n", 2)
+	if !strings.HasPrefix(parts[0], "Subject: ") {
+		return Message{}, ErrNoSubject
+	}
+	m := Message{
+		From:       from,
+		To:         b.Form.Email,
+		Subject:    strings.TrimSpace(strings.TrimPrefix(parts[0], "Subject: ")),
+		BookingRef: b.Form.BookingRef,
+		Kind:       kind,
+	}
+	if len(parts) > 1 {
+		m.Body = strings.TrimLeft(parts[1], "\n")
+	}
+	return m, nil
+}
+
+// dueEmails returns the kinds of email due to be sent on a day about a
+// booking: the confirmation on the day it was booked, the reminder
+// preArrivalDays before arrival, and the thank-you the day after departure.
+// Only guests with an email address who are staying are emailed.
+func dueEmails(b Booking, day time.Time, preArrivalDays int) []string {
+	if b.Form.Email == "" || !b.Form.Status.takesPlace() {
+		return nil
+	}
+	var kinds []string
+	if sameDay(b.Form.BookingDate, day) {
+		kinds = append(kinds, ConfirmationEmail)
+	}
+	if sameDay(b.Arrival.AddDate(0, 0, -preArrivalDays), day) {
+		kinds = append(kinds, PreArrivalEmail)
+	}
+	if sameDay(b.Departure.AddDate(0, 0, 1), day) {
+		kinds = append(kinds, ThankYouEmail)
+	}
+	return kinds
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+// SendDueEmails sends the emails due on a day about the bookings in the
+// store, returning how many it sent
+func SendDueEmails(store BookingStore, settings Settings, mailer Mailer, day time.Time) (int, error) {
+	bookings, err := store.List(BookingFilter{})
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, b := range bookings {
+		for _, kind := range dueEmails(b, day, settings.preArrivalDays()) {
+			m, err := RenderEmail(b, kind, settings.MailFrom)
+			if err != nil {
+				return sent, fmt.Errorf("%s %s: %v", b.Form.BookingRef, kind, err)
+			}
+			if err = mailer.Send(m); err != nil {
+				return sent, err
+			}
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// checkEmailTemplates parses each of the property's email templates
+func (p Property) checkEmailTemplates() error {
+	for _, kind := range emailKinds {
+		text, err := p.emailTemplate(kind)
+		if err == nil {
+			_, err = template.New(kind).Funcs(emailFuncs).Parse(text)
+		}
+		if err != nil {
+			return fmt.Errorf("%s email: %v", kind, err)
+		}
+	}
+	return nil
+}
+
+// preArrivalDays returns how many days before arrival the reminder is sent
+func (s Settings) preArrivalDays() int {
+	if s.PreArrivalDays == 0 {
+		return 7
+	}
+	return s.PreArrivalDays
+}
@@ -0,0 +1,110 @@
+package booking
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryMailer keeps the emails it is sent
+type memoryMailer struct {
+	sent []Message
+}
+
+func (m *memoryMailer) Send(msg Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestRenderEmail(t *testing.T) {
+	b := guestBooking("6FBJUN1719", "ann@example.com", "", 400)
+	m, err := RenderEmail(b, ConfirmationEmail, "stay@example.com")
+	if err != nil {
+		t.Fatalf("RenderEmail() error = %v", err)
+	}
+	if m.Subject != "Your booking at FooBarBaz (6FBJUN1719)" || m.To != "ann@example.com" {
+		t.Errorf("RenderEmail() = %+v", m)
+	}
+	if !strings.HasPrefix(m.Body, "Dear Ann,") || !strings.Contains(m.Body, "Saturday 17 June 2017") {
+		t.Errorf("RenderEmail() body = %q", m.Body)
+	}
+
+	dir := t.TempDir()
+	b.Property.TemplateDir = dir
+	ioutil.WriteFile(filepath.Join(dir, "thank_you.tmpl"), []byte("Subject: Thanks {{.Form.FirstName}}\n\nPaid {{money .Retained}}\n"), 0644)
+	if m, err = RenderEmail(b, ThankYouEmail, ""); err != nil || m.Subject != "Thanks Ann" || m.Body != "Paid 400.00\n" {
+		t.Errorf("RenderEmail() property template = %+v, %v", m, err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "pre_arrival.tmpl"), []byte("Hello\n"), 0644)
+	if _, err = RenderEmail(b, PreArrivalEmail, ""); err != ErrNoSubject {
+		t.Errorf("RenderEmail() without subject error = %v, want %v", err, ErrNoSubject)
+	}
+}
+
+func TestSendDueEmails(t *testing.T) {
+	store := NewMemoryStore()
+	// booked 1 May, arriving 8 June, departing 10 June
+	store.Create(guestBooking("6FBJUN0810", "ann@example.com", "", 300))
+	store.Create(guestBooking("6WWJUN0810", "", "", 300))
+	tests := []struct {
+		day  time.Time
+		want []string
+	}{
+		{Datetime(2017, time.May, 1), []string{ConfirmationEmail}},
+		{Datetime(2017, time.June, 1), []string{PreArrivalEmail}},
+		{Datetime(2017, time.June, 11), []string{ThankYouEmail}},
+		{Datetime(2017, time.June, 12), nil},
+	}
+	for _, tt := range tests {
+		mailer := &memoryMailer{}
+		if _, err := SendDueEmails(store, Settings{}, mailer, tt.day); err != nil {
+			t.Fatalf("SendDueEmails() error = %v", err)
+		}
+		var kinds []string
+		for _, m := range mailer.sent {
+			kinds = append(kinds, m.Kind)
+		}
+		if strings.Join(kinds, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("SendDueEmails(%s) = %v, want %v", tt.day.Format(DateLayout), kinds, tt.want)
+		}
+	}
+}
+
+func TestEMLMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	mailer := EMLMailer{Dir: dir, Now: func() time.Time { return Datetime(2017, time.May, 1) }}
+	msg := Message{From: "stay@example.com", To: "ann@example.com", Subject: "Hello",
+		Body: "Dear Ann,\n", BookingRef: "6FBJUN1719", Kind: ConfirmationEmail}
+	if err := mailer.Send(msg); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	eml, err := ioutil.ReadFile(filepath.Join(dir, "6FBJUN1719-confirmation-20170501-000000.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "From: stay@example.com\r\nTo: ann@example.com\r\nSubject: Hello\r\n" +
+		"Date: Mon, 01 May 2017 00:00:00 +0000\r\nMIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n\r\nDear Ann,\r\n"
+	if string(eml) != want {
+		t.Errorf("Send() wrote %q, want %q", eml, want)
+	}
+
+	// sent again, with a subject that is not ASCII
+	msg.Subject = "Your stay at Château Brûlé"
+	if err := mailer.Send(msg); err != nil {
+		t.Fatalf("Send() again error = %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 2 {
+		t.Fatalf("Send() twice wrote %v, want 2 files", files)
+	}
+	eml, err = ioutil.ReadFile(filepath.Join(dir, "6FBJUN1719-confirmation-20170501-000000-2.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject := "Subject: =?utf-8?q?Your_stay_at_Ch=C3=A2teau_Br=C3=BBl=C3=A9?=\r\n"; !strings.Contains(string(eml), subject) {
+		t.Errorf("Send() wrote %q, want it to contain %q", eml, subject)
+	}
+}
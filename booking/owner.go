@@ -0,0 +1,120 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+/*
+ * House owners are paid the owner income of the bookings at their
+ * properties, on a payout schedule, to their bank reference:
+ *
+ * "owners": [
+ *   { "id" : "jones",
+ *     "name" : "Mr and Mrs Jones",
+ *     "bank_reference" : "20-00-00 12345678",
+ *     "properties" : ["FB", "WW"],
+ *     "payout" : { "every" : "month", "day" : 1, "minimum" : 50 } }
+ * ]
+ *
+ * Payouts are made every "week", on day 0 (Sunday) to 6, or every "month",
+ * on day 1 to 28, or whenever payouts are made if there is no schedule.
+ * Balances under the minimum are carried over to the next payout. What each
+ * owner is owed is kept in a ledger, see ledger.go.
+ */
+
+// PayoutSchedule says when a house owner is paid
+type PayoutSchedule struct {
+	// Every is "week" or "month", or "" to pay whenever payouts are made
+	Every string `json:"every"`
+	// Day is the day of the week, or of the month, payouts are made on
+	Day int `json:"day"`
+	// Minimum holds back payouts of less than it
+	Minimum float64 `json:"minimum"`
+}
+
+// HouseOwner owns properties, and is paid their owner income
+type HouseOwner struct {
+	ID            string         `json:"id"`
+	Name          string         `json:"name"`
+	BankReference string         `json:"bank_reference"`
+	Properties    []string       `json:"properties"`
+	Payout        PayoutSchedule `json:"payout"`
+}
+
+// ErrOwnerNotFound is returned for an id no house owner has
+var ErrOwnerNotFound = errors.New("house owner not found")
+
+// due reports whether a payout is due on day
+func (p PayoutSchedule) due(day time.Time) bool {
+	switch p.Every {
+	case "week":
+		return int(day.Weekday()) == p.Day
+	case "month":
+		return day.Day() == p.Day
+	}
+	return true
+}
+
+func (p PayoutSchedule) validate() error {
+	switch {
+	case p.Every == "week" && (p.Day < 0 || p.Day > 6):
+		return fmt.Errorf("weekly payouts are on day 0 (Sunday) to 6, not %d", p.Day)
+	case p.Every == "month" && (p.Day < 1 || p.Day > 28):
+		return fmt.Errorf("monthly payouts are on day 1 to 28, not %d", p.Day)
+	case p.Every != "" && p.Every != "week" && p.Every != "month":
+		return fmt.Errorf("payouts are every week or month, not %q", p.Every)
+	}
+	return nil
+}
+
+// Owner returns the house owner with id
+func (s Settings) Owner(id string) (HouseOwner, error) {
+	for _, o := range s.Owners {
+		if o.ID == id {
+			return o, nil
+		}
+	}
+	return HouseOwner{}, ErrOwnerNotFound
+}
+
+// OwnerOf returns the house owner of a property, and false if it has none
+func (s Settings) OwnerOf(shortName string) (HouseOwner, bool) {
+	for _, o := range s.Owners {
+		if containsString(o.Properties, shortName) {
+			return o, true
+		}
+	}
+	return HouseOwner{}, false
+}
+
+// validateOwners checks every owner has an id of their own and a payout
+// schedule, and owns properties that no one else does
+func (s Settings) validateOwners() error {
+	ids := make(map[string]bool)
+	owners := make(map[string]string)
+	for _, o := range s.Owners {
+		if o.ID == "" || ids[o.ID] {
+			return fmt.Errorf("house owner %q: ids must be given, and different", o.ID)
+		}
+		ids[o.ID] = true
+		if err := o.Payout.validate(); err != nil {
+			return fmt.Errorf("house owner %s: %v", o.ID, err)
+		}
+		for _, p := range o.Properties {
+			if other, ok := owners[p]; ok {
+				return fmt.Errorf("house owners %s and %s both own %s", other, o.ID, p)
+			}
+			owners[p] = o.ID
+			found := false
+			for _, property := range s.Properties {
+				found = found || property.ShortName == p
+			}
+			if !found {
+				return fmt.Errorf("house owner %s: no property %s", o.ID, p)
+			}
+		}
+	}
+	return nil
+}
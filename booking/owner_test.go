@@ -0,0 +1,78 @@
+package booking
+
+import (
+	"testing"
+	"time"
+)
+
+func ownerSettings() Settings {
+	settings := testSettings
+	settings.Owners = []HouseOwner{
+		{ID: "jones", Name: "Mr and Mrs Jones", BankReference: "20-00-00 12345678", Properties: []string{"FB"},
+			Payout: PayoutSchedule{Every: "month", Day: 1, Minimum: 50}},
+		{ID: "smith", Name: "Ms Smith", BankReference: "40-00-00 87654321", Properties: []string{"WW"}},
+	}
+	return settings
+}
+
+func TestSettings_validateOwners(t *testing.T) {
+	tests := []struct {
+		name    string
+		owners  []HouseOwner
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"valid", ownerSettings().Owners, false},
+		{"weekly", []HouseOwner{{ID: "jones", Payout: PayoutSchedule{Every: "week", Day: 5}}}, false},
+		{"no id", []HouseOwner{{Properties: []string{"FB"}}}, true},
+		{"same id", []HouseOwner{{ID: "jones"}, {ID: "jones"}}, true},
+		{"shared property", []HouseOwner{{ID: "jones", Properties: []string{"FB"}},
+			{ID: "smith", Properties: []string{"FB"}}}, true},
+		{"unknown property", []HouseOwner{{ID: "jones", Properties: []string{"XX"}}}, true},
+		{"unknown schedule", []HouseOwner{{ID: "jones", Payout: PayoutSchedule{Every: "fortnight"}}}, true},
+		{"day of month", []HouseOwner{{ID: "jones", Payout: PayoutSchedule{Every: "month", Day: 31}}}, true},
+		{"day of week", []HouseOwner{{ID: "jones", Payout: PayoutSchedule{Every: "week", Day: 7}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := testSettings
+			settings.Owners = tt.owners
+			if err := settings.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPayoutSchedule_due(t *testing.T) {
+	friday := Datetime(2017, time.July, 7)
+	tests := []struct {
+		name     string
+		schedule PayoutSchedule
+		day      time.Time
+		want     bool
+	}{
+		{"whenever", PayoutSchedule{}, friday, true},
+		{"weekly", PayoutSchedule{Every: "week", Day: 5}, friday, true},
+		{"weekly other day", PayoutSchedule{Every: "week", Day: 1}, friday, false},
+		{"monthly", PayoutSchedule{Every: "month", Day: 1}, Datetime(2017, time.July, 1), true},
+		{"monthly other day", PayoutSchedule{Every: "month", Day: 1}, friday, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.due(tt.day); got != tt.want {
+				t.Errorf("due() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSettings_OwnerOf(t *testing.T) {
+	settings := ownerSettings()
+	if o, ok := settings.OwnerOf("WW"); !ok || o.ID != "smith" {
+		t.Errorf("OwnerOf(WW) = %v, %v", o, ok)
+	}
+	if _, err := settings.Owner("brown"); err != ErrOwnerNotFound {
+		t.Errorf("Owner(brown) error = %v, want %v", err, ErrOwnerNotFound)
+	}
+}
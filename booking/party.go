@@ -0,0 +1,90 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+/*
+ * Laundry and consumables are priced by party size. The "laundry" and
+ * "consumables" lists price parties of one, two and so on, with larger
+ * parties paying the last price. For anything else, a property can price
+ * them in bands instead, surcharging each guest beyond the largest band:
+ *
+ * "laundry_by_party": {
+ *   "bands" : [ { "max_people" : 2, "amount" : 10 },
+ *               { "max_people" : 4, "amount" : 15 },
+ *               { "max_people" : 6, "amount" : 25 } ],
+ *   "extra_person" : 5
+ * },
+ * "max_occupancy" : 8
+ */
+
+// PartyBand prices parties of up to MaxPeople, and more than the band before
+type PartyBand struct {
+	MaxPeople int     `json:"max_people"`
+	Amount    float64 `json:"amount"`
+}
+
+// PartyPricing prices something by the size of the party
+type PartyPricing struct {
+	Bands []PartyBand `json:"bands"`
+	// ExtraPerson is added for each guest beyond the largest band
+	ExtraPerson float64 `json:"extra_person"`
+}
+
+// pricingByPeople turns a list of prices for one, two and so on people into
+// bands, the largest of which covers any larger party
+func pricingByPeople(prices []float64) PartyPricing {
+	var pricing PartyPricing
+	for i, amount := range prices {
+		pricing.Bands = append(pricing.Bands, PartyBand{MaxPeople: i + 1, Amount: amount})
+	}
+	return pricing
+}
+
+// price returns the price for a party of people
+func (pp PartyPricing) price(people int) float64 {
+	if len(pp.Bands) == 0 {
+		return 0
+	}
+	bands := append([]PartyBand(nil), pp.Bands...)
+	sort.Slice(bands, func(i, j int) bool { return bands[i].MaxPeople < bands[j].MaxPeople })
+	for _, band := range bands {
+		if people <= band.MaxPeople {
+			return band.Amount
+		}
+	}
+	largest := bands[len(bands)-1]
+	return largest.Amount + pp.ExtraPerson*float64(people-largest.MaxPeople)
+}
+
+func (p Property) laundryPricing() PartyPricing {
+	if p.LaundryByParty != nil {
+		return *p.LaundryByParty
+	}
+	return pricingByPeople(p.Laundry)
+}
+
+func (p Property) consumablesPricing() PartyPricing {
+	if p.ConsumablesByParty != nil {
+		return *p.ConsumablesByParty
+	}
+	return pricingByPeople(p.Consumables)
+}
+
+// ErrNoGuests is returned for bookings without anyone staying
+var ErrNoGuests = errors.New("a booking needs at least 1 person")
+
+// CheckPartySize returns an error if a party of people cannot stay at the
+// property. A property without a maximum occupancy takes any size of party.
+func (p Property) CheckPartySize(people int) error {
+	if people < 1 {
+		return ErrNoGuests
+	}
+	if p.MaxOccupancy > 0 && people > p.MaxOccupancy {
+		return fmt.Errorf("%s sleeps at most %d people", p.LongName, p.MaxOccupancy)
+	}
+	return nil
+}
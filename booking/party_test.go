@@ -0,0 +1,93 @@
+package booking
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPartyPricing_price(t *testing.T) {
+	banded := PartyPricing{
+		Bands: []PartyBand{
+			{MaxPeople: 4, Amount: 15},
+			{MaxPeople: 2, Amount: 10},
+			{MaxPeople: 6, Amount: 25},
+		},
+		ExtraPerson: 5,
+	}
+	tests := []struct {
+		name    string
+		pricing PartyPricing
+		people  int
+		want    float64
+	}{
+		{"smallest band", banded, 1, 10},
+		{"top of band", banded, 2, 10},
+		{"next band", banded, 3, 15},
+		{"largest band", banded, 6, 25},
+		{"extra people", banded, 8, 35},
+		{"by people", pricingByPeople([]float64{10, 10, 15, 15, 25, 25}), 3, 15},
+		{"by people, larger party", pricingByPeople([]float64{10, 10, 15, 15, 25, 25}), 8, 25},
+		{"no bands", PartyPricing{}, 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.pricing.price(tt.people); got != tt.want {
+				t.Errorf("price() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProperty_checkPartySize(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       Property
+		people  int
+		wantErr bool
+	}{
+		{"no limit", Property{}, 12, false},
+		{"within limit", Property{MaxOccupancy: 8}, 8, false},
+		{"over limit", Property{MaxOccupancy: 8}, 9, true},
+		{"nobody", Property{}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.p.CheckPartySize(tt.people); (err != nil) != tt.wantErr {
+				t.Errorf("CheckPartySize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_createBooking_partySize(t *testing.T) {
+	settings := Settings{Properties: []Property{{ShortName: "FB", Laundry: []float64{10, 10, 15, 15, 25, 25},
+		ConsumablesByParty: &PartyPricing{Bands: []PartyBand{{MaxPeople: 6, Amount: 35}}, ExtraPerson: 5}}}}
+	f := FormInput{BookingRef: "6FBJUN1719", IsLaundry: true, IsConsumables: true}
+	for people, want := range map[int]float64{0: 45, 1: 45, 6: 60, 8: 70} {
+		f.NumberOfPeople = people
+		b := CreateBooking(f, settings)
+		if got := LineItemTotal(b.LineItems, PerStay); got != want {
+			t.Errorf("CreateBooking() for %d people services = %v, want %v", people, got, want)
+		}
+	}
+}
+
+func TestImportCSV_overCapacity(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "bookings.csv")
+	csv := "6FBJUN1719,FooBarBaz,Ada,Lovelace,,,,2017-05-20,airbnb,,,4,400\n" +
+		"6FBJUL0108,FooBarBaz,Alan,Turing,,,,2017-05-21,email,,,9,900\n"
+	os.WriteFile(file, []byte(csv), 0644)
+	settings := Settings{Properties: []Property{{ShortName: "FB", MaxOccupancy: 6}}}
+	store := NewMemoryStore()
+	report, err := ImportCSV(file, settings, store)
+	if err != nil {
+		t.Fatalf("ImportCSV() error = %v", err)
+	}
+	if report.Imported != 1 || len(report.Rejected) != 1 || report.Rejected[0].BookingRef != "6FBJUL0108" {
+		t.Errorf("ImportCSV() = %+v, want 6FBJUL0108 rejected", report)
+	}
+	if _, err := store.Get("6FBJUL0108"); err != ErrBookingNotFound {
+		t.Errorf("rejected booking was stored")
+	}
+}
@@ -0,0 +1,59 @@
+package booking
+
+import "time"
+
+// PaymentTerm says when the money for a booking is due, as a number of days
+// after one of the booking's dates, e.g. the balance due 42 days before
+// arrival is {"from": "arrival", "days": -42}, and a channel paying out the
+// day after departure is {"source": "airbnb", "from": "departure", "days": 1}
+type PaymentTerm struct {
+	// Source is the name of the source the term applies to, or empty for any source
+	Source string `json:"source"`
+	// From is "booking", "arrival" or "departure"
+	From string `json:"from"`
+	Days int    `json:"days"`
+}
+
+// paymentTerm returns the term for bookings from source: the first term for
+// that source, else the first term for any source. Without either, payment
+// is due on the booking date.
+func (p Property) paymentTerm(source Source) PaymentTerm {
+	for _, t := range p.PaymentTerms {
+		if t.Source == source.String() {
+			return t
+		}
+	}
+	for _, t := range p.PaymentTerms {
+		if t.Source == "" {
+			return t
+		}
+	}
+	return PaymentTerm{From: "booking"}
+}
+
+// dueDate returns when payment is due for a booking made on bookingDate
+func (t PaymentTerm) dueDate(bookingDate, arrival, departure time.Time) time.Time {
+	from := bookingDate
+	switch t.From {
+	case "arrival":
+		from = arrival
+	case "departure":
+		from = departure
+	}
+	return from.AddDate(0, 0, t.Days)
+}
+
+// agencyCommissionApplies reports whether the agency takes its commission on a
+// booking. It does not on bookings the owner took directly, nor on bookings
+// from sources the property exempts.
+func (p Property) agencyCommissionApplies(f FormInput) bool {
+	if f.IsOwnerDirect {
+		return false
+	}
+	for _, s := range p.CommissionFreeSources {
+		if s == f.Source.String() {
+			return false
+		}
+	}
+	return true
+}
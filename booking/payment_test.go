@@ -0,0 +1,75 @@
+package booking
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProperty_paymentTerm(t *testing.T) {
+	p := Property{PaymentTerms: []PaymentTerm{
+		{Source: "airbnb", From: "departure", Days: 1},
+		{From: "arrival", Days: -42},
+		{Source: "booking.com", From: "departure", Days: 30},
+	}}
+	tests := []struct {
+		name   string
+		p      Property
+		source Source
+		want   PaymentTerm
+	}{
+		{"source term", p, AirBnb, PaymentTerm{Source: "airbnb", From: "departure", Days: 1}},
+		{"source term after default", p, BookingCom, PaymentTerm{Source: "booking.com", From: "departure", Days: 30}},
+		{"default term", p, Email, PaymentTerm{From: "arrival", Days: -42}},
+		{"no terms", Property{}, Email, PaymentTerm{From: "booking"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p.paymentTerm(tt.source); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paymentTerm() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaymentTerm_dueDate(t *testing.T) {
+	booked := Datetime(2017, time.May, 20)
+	arrival := Datetime(2017, time.June, 17)
+	departure := Datetime(2017, time.June, 19)
+	tests := []struct {
+		name string
+		term PaymentTerm
+		want time.Time
+	}{
+		{"on booking", PaymentTerm{From: "booking"}, booked},
+		{"before arrival", PaymentTerm{From: "arrival", Days: -14}, Datetime(2017, time.June, 3)},
+		{"after departure", PaymentTerm{From: "departure", Days: 14}, Datetime(2017, time.July, 3)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.term.dueDate(booked, arrival, departure); !got.Equal(tt.want) {
+				t.Errorf("dueDate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProperty_agencyCommissionApplies(t *testing.T) {
+	p := Property{CommissionFreeSources: []string{"other"}}
+	tests := []struct {
+		name string
+		f    FormInput
+		want bool
+	}{
+		{"agency booking", FormInput{Source: AirBnb}, true},
+		{"owner direct", FormInput{Source: Email, IsOwnerDirect: true}, false},
+		{"commission free source", FormInput{Source: Other}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.agencyCommissionApplies(tt.f); got != tt.want {
+				t.Errorf("agencyCommissionApplies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// defaultCountry is the country phone numbers without a country code are in
+const defaultCountry = "GB"
+
+// callingCodes are the international calling codes of the countries guests
+// commonly book from, by ISO 3166 country code
+var callingCodes = map[string]string{
+	"AU": "61",
+	"BE": "32",
+	"CA": "1",
+	"CH": "41",
+	"DE": "49",
+	"ES": "34",
+	"FR": "33",
+	"GB": "44",
+	"IE": "353",
+	"IT": "39",
+	"NL": "31",
+	"NZ": "64",
+	"US": "1",
+}
+
+// ErrInvalidPhone is returned for phone numbers that cannot be normalised
+var ErrInvalidPhone = errors.New("not a valid phone number")
+
+// NormalisePhone returns number in E.164 form, e.g. "+447700900123". Numbers
+// without a country code, e.g. "07700 900123", are taken to be in country.
+func NormalisePhone(number, country string) (string, error) {
+	number = strings.TrimSpace(number)
+	if number == "" {
+		return "", nil
+	}
+	if strings.HasPrefix(number, "+") {
+		// the trunk prefix is often written after the country code, e.g. "+44 (0)7700"
+		number = strings.Replace(number, "(0)", "", 1)
+	}
+	var digits strings.Builder
+	for i, r := range number {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+	d := digits.String()
+	switch {
+	case strings.HasPrefix(number, "+"):
+	case strings.HasPrefix(d, "00"):
+		d = d[2:]
+	default:
+		code, ok := callingCodes[strings.ToUpper(country)]
+		if !ok {
+			return "", fmt.Errorf("no calling code for country %q", country)
+		}
+		// drop the trunk prefix dialled within the country
+		d = code + strings.TrimPrefix(d, "0")
+	}
+	if len(d) < 8 || len(d) > 15 || d[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+	return "+" + d, nil
+}
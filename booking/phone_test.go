@@ -0,0 +1,34 @@
+package booking
+
+import "testing"
+
+func Test_normalisePhone(t *testing.T) {
+	tests := []struct {
+		number  string
+		country string
+		want    string
+		wantErr bool
+	}{
+		{"07700 900123", "GB", "+447700900123", false},
+		{"+44 (0)7700 900123", "GB", "+447700900123", false},
+		{"+44 7700-900-123", "FR", "+447700900123", false},
+		{"0044 7700 900123", "GB", "+447700900123", false},
+		{"06 12 34 56 78", "FR", "+33612345678", false},
+		{"(555) 123.4567", "US", "+15551234567", false},
+		{"", "GB", "", false},
+		{"07700 900123 ext 4", "GB", "", true},
+		{"0770", "GB", "", true},
+		{"07700 900123", "XX", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			got, err := NormalisePhone(tt.number, tt.country)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalisePhone() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalisePhone() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package booking_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/tintinnabulate/supreme-garbanzo/booking"
+	"github.com/tintinnabulate/supreme-garbanzo/generators"
+)
+
+// settlementOrder ranks the kinds of line item in the order the settlement
+// model takes them: the channel fee, agency and house owner commissions, and
+// then the services
+var settlementOrder = map[string]int{
+	booking.ChannelCommission:    0,
+	booking.AgencyCommission:     1,
+	booking.HouseOwnerCommission: 2,
+}
+
+// houseOwnerMinimum is the default settlement's house owner commission minimum
+const houseOwnerMinimum = 35
+
+// randomStay varies a random booking with a discount, a refund on cancelling,
+// or a guest who does not turn up
+func randomStay(r *rand.Rand, gs generators.Settings) (booking.FormInput, booking.Settings) {
+	f := booking.FormInput(generators.RandomFormInput(r, gs))
+	settings := booking.Settings(gs)
+	switch r.Intn(3) {
+	case 0:
+		f.Discount = booking.Discount{Kind: booking.PercentDiscount, Amount: r.Float64()}
+	case 1:
+		f.Discount = booking.Discount{Kind: booking.FixedDiscount, Amount: float64(r.Intn(50000)) / 100}
+	}
+	f.Status = []booking.Status{booking.Confirmed, booking.Completed, booking.Cancelled, booking.NoShow}[r.Intn(4)]
+	if f.Status == booking.Cancelled {
+		f.CancellationDate = f.BookingDate
+		settings.Properties = append([]booking.Property(nil), settings.Properties...)
+		for i := range settings.Properties {
+			settings.Properties[i].CancellationPolicy = []booking.CancellationTier{{Refund: r.Float64()}}
+		}
+	}
+	return f, settings
+}
+
+func TestQuick_settlement(t *testing.T) {
+	within := func(a, b float64) bool { return math.Abs(a-b) < 0.005 }
+	settles := func(gs generators.Settings, seed int64) bool {
+		f, settings := randomStay(rand.New(rand.NewSource(seed)), gs)
+		b := booking.CreateBooking(f, settings)
+		takesPlace := f.Status != booking.Cancelled && f.Status != booking.NoShow
+		if b.BookingFee < 0 || b.TotalFees < 0 {
+			t.Logf("%s: booking fee %.2f, fees %.2f, want neither negative", f.BookingRef, b.BookingFee, b.TotalFees)
+			return false
+		}
+		if b.DiscountAmount < 0 || b.Refund < 0 || !within(b.Retained, f.Gross-b.DiscountAmount-b.Refund) {
+			t.Logf("%s: retained %.2f of %.2f, less a discount of %.2f and refund of %.2f", f.BookingRef,
+				b.Retained, f.Gross, b.DiscountAmount, b.Refund)
+			return false
+		}
+		if b.Refund > 0 && f.Status != booking.Cancelled {
+			t.Logf("%s: refunded %.2f on a %s booking", f.BookingRef, b.Refund, f.Status)
+			return false
+		}
+		rank, net := 0, b.Retained
+		for _, item := range b.LineItems {
+			if item.Amount < 0 && item.Kind != booking.LengthOfStay {
+				t.Logf("%s: %s is %.2f, want it not negative", f.BookingRef, item.Name, item.Amount)
+				return false
+			}
+			next, ok := settlementOrder[item.Kind]
+			if !ok {
+				next = len(settlementOrder)
+			}
+			if next < rank {
+				t.Logf("%s: %s is taken after a later deduction", f.BookingRef, item.Name)
+				return false
+			}
+			rank = next
+			if !ok {
+				if !takesPlace {
+					t.Logf("%s: %s charged on a %s booking", f.BookingRef, item.Name, f.Status)
+					return false
+				}
+				continue
+			}
+			// each commission is charged on what the deductions before it left
+			if !within(item.Base, net) {
+				t.Logf("%s: %s is charged on %.2f, want %.2f", f.BookingRef, item.Name, item.Base, net)
+				return false
+			}
+			want := item.Rate * item.Base
+			if item.Kind == booking.HouseOwnerCommission && takesPlace {
+				want = math.Max(houseOwnerMinimum, want)
+			}
+			if !within(item.Amount, want) {
+				t.Logf("%s: %s on a %s booking is %.2f, want %.2f", f.BookingRef, item.Name, f.Status, item.Amount, want)
+				return false
+			}
+			net -= item.Amount
+		}
+		return true
+	}
+	if err := quick.Check(settles, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQuick_bookingRefRoundTrip(t *testing.T) {
+	roundTrips := func(gs generators.Settings, seed int64) bool {
+		f := generators.RandomFormInput(rand.New(rand.NewSource(seed)), gs)
+		b := booking.CreateBooking(booking.FormInput(f), booking.Settings(gs))
+		if got := booking.CreateBookingRef(b, booking.Settings(gs)); got != f.BookingRef {
+			t.Logf("CreateBookingRef() = %v, want %v", got, f.BookingRef)
+			return false
+		}
+		return b.Departure.After(b.Arrival)
+	}
+	if err := quick.Check(roundTrips, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQuick_bookingRef(t *testing.T) {
+	decodes := func(ref generators.BookingRef) bool {
+		b := booking.CreateBooking(booking.FormInput{BookingRef: string(ref)}, booking.Settings{})
+		nights := booking.NightsBetween(b.Arrival, b.Departure)
+		return nights >= 1 && nights <= 27
+	}
+	if err := quick.Check(decodes, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,266 @@
+package booking
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+/*
+ * Reconciling a bank statement matches the money that came in to the
+ * bookings it pays for. A transaction is matched to a booking when its
+ * description gives the booking reference, or, failing that, when it is
+ * exactly what one booking still owes, and arrived within some days of the
+ * booking's due date. What a booking owes is its Net, what a channel pays
+ * out, or its gross, what the guest paid less any discount and refund: a
+ * booking is paid once it has received at least its Net.
+ *
+ * Matched transactions are recorded as receipts of their bookings, in the
+ * store, and are skipped if the statement is reconciled again. Transactions
+ * giving a reference for less than the booking owes are partial payments,
+ * and are recorded too. Money going out of the bank is not reconciled.
+ */
+
+// DefaultReconcileWindow is how many days either side of its due date a
+// payment without a booking reference is matched to a booking
+const DefaultReconcileWindow = 7
+
+// Receipt is a payment for a booking, found in the bank
+type Receipt struct {
+	Date          time.Time
+	Amount        float64
+	TransactionID string
+	Description   string
+}
+
+// Received returns how much has been paid for the booking
+func (b Booking) Received() float64 {
+	total := 0.0
+	for _, r := range b.Receipts {
+		total += r.Amount
+	}
+	return pence(total)
+}
+
+// Outstanding returns how much the booking still owes, before it is paid
+func (b Booking) Outstanding() float64 {
+	if owed := pence(b.Net - b.Received()); owed > 0 {
+		return owed
+	}
+	return 0
+}
+
+// IsPaid reports whether the booking has received at least its Net
+func (b Booking) IsPaid() bool {
+	return b.Outstanding() == 0
+}
+
+// expectsPayment reports whether the booking is owed money at all
+func (b Booking) expectsPayment() bool {
+	return b.Form.Status != Enquiry && b.Net > 0
+}
+
+// ReconciledItem is a transaction matched to a booking, with the booking as
+// it is after the transaction
+type ReconciledItem struct {
+	Transaction Transaction
+	Booking     Booking
+}
+
+// UnmatchedTransaction is a transaction that pays for no booking, and why
+type UnmatchedTransaction struct {
+	Transaction Transaction
+	Reason      string
+}
+
+// Reconciliation is what reconciling a bank statement found
+type Reconciliation struct {
+	From, To time.Time
+	// Matched transactions paid for their booking in full
+	Matched []ReconciledItem
+	// Partial transactions left their booking owing
+	Partial []ReconciledItem
+	// Unmatched transactions could not be told to be for any one booking
+	Unmatched []UnmatchedTransaction
+	// Unpaid bookings were due during the statement, but are still owed
+	Unpaid []Booking
+	// AlreadyRecorded counts transactions reconciled before
+	AlreadyRecorded int
+}
+
+// Reconcile matches the money coming in on a bank statement to the stored
+// bookings, recording the receipts of those it matches. window is how many
+// days either side of its due date a payment without a reference can arrive.
+func Reconcile(store BookingStore, transactions []Transaction, window int) (Reconciliation, error) {
+	var r Reconciliation
+	all, err := store.List(BookingFilter{})
+	if err != nil {
+		return r, err
+	}
+	var bookings []*Booking
+	recorded := make(map[string]bool)
+	for i := range all {
+		for _, receipt := range all[i].Receipts {
+			recorded[receipt.TransactionID] = true
+		}
+		if all[i].expectsPayment() {
+			bookings = append(bookings, &all[i])
+		}
+	}
+	for _, t := range transactions {
+		if r.From.IsZero() || t.Date.Before(r.From) {
+			r.From = t.Date
+		}
+		if t.Date.After(r.To) {
+			r.To = t.Date
+		}
+		if t.Amount <= 0 {
+			continue
+		}
+		if recorded[t.ID] || recorded[redactedID(t.ID)] {
+			r.AlreadyRecorded++
+			continue
+		}
+		b, reason := matchTransaction(t, bookings, window)
+		if b == nil {
+			r.Unmatched = append(r.Unmatched, UnmatchedTransaction{Transaction: t, Reason: reason})
+			continue
+		}
+		b.Receipts = append(append([]Receipt(nil), b.Receipts...),
+			Receipt{Date: t.Date, Amount: t.Amount, TransactionID: t.ID, Description: t.Description})
+		if err := store.Update(*b); err != nil {
+			return r, err
+		}
+		recorded[t.ID] = true
+		item := ReconciledItem{Transaction: t, Booking: *b}
+		if b.IsPaid() {
+			r.Matched = append(r.Matched, item)
+		} else {
+			r.Partial = append(r.Partial, item)
+		}
+	}
+	for _, b := range bookings {
+		if !b.IsPaid() && !b.DueDate.After(r.To) && !b.DueDate.Before(r.From.AddDate(0, 0, -window)) {
+			r.Unpaid = append(r.Unpaid, *b)
+		}
+	}
+	sort.SliceStable(r.Unpaid, func(i, j int) bool { return r.Unpaid[i].DueDate.Before(r.Unpaid[j].DueDate) })
+	return r, nil
+}
+
+// matchTransaction returns the booking a transaction pays for, or why there
+// is none
+func matchTransaction(t Transaction, bookings []*Booking, window int) (*Booking, string) {
+	var named []*Booking
+	description := strings.ToUpper(t.Description)
+	for _, b := range bookings {
+		if containsRef(description, strings.ToUpper(b.Form.BookingRef)) {
+			named = append(named, b)
+		}
+	}
+	switch {
+	case len(named) == 1:
+		b := named[0]
+		if t.Amount > pence(b.Retained-b.Received()) {
+			return nil, fmt.Sprintf("more than %s owes", b.Form.BookingRef)
+		}
+		return b, ""
+	case len(named) > 1:
+		return nil, fmt.Sprintf("gives %d booking references", len(named))
+	}
+	var candidates []string
+	var match *Booking
+	for _, b := range bookings {
+		if b.IsPaid() || !withinDays(t.Date, b.DueDate, window) {
+			continue
+		}
+		received := b.Received()
+		if t.Amount == pence(b.Net-received) || t.Amount == pence(b.Retained-received) {
+			candidates = append(candidates, b.Form.BookingRef)
+			match = b
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, "no booking owes this"
+	case 1:
+		return match, ""
+	}
+	return nil, "could be any of " + strings.Join(candidates, ", ")
+}
+
+// containsRef reports whether a booking reference is in a description as a
+// word of its own, so 6FBJUN1719 is not found in 16FBJUN1719
+func containsRef(description, ref string) bool {
+	for i := strings.Index(description, ref); i >= 0; {
+		end := i + len(ref)
+		if (i == 0 || !isAlphanumeric(description[i-1])) && (end == len(description) || !isAlphanumeric(description[end])) {
+			return true
+		}
+		next := strings.Index(description[i+1:], ref)
+		if next < 0 {
+			break
+		}
+		i += 1 + next
+	}
+	return false
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+// withinDays reports whether two dates are at most days apart
+func withinDays(a, b time.Time, days int) bool {
+	diff := a.Sub(b)
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= time.Duration(days)*24*time.Hour
+}
+
+// WriteReconciliation writes a reconciliation as aligned plain text tables
+func WriteReconciliation(out io.Writer, r Reconciliation) error {
+	fmt.Fprintf(out, "Bank reconciliation from %s to %s\n", r.From.Format(DateLayout), r.To.Format(DateLayout))
+	if r.AlreadyRecorded > 0 {
+		fmt.Fprintf(out, "Reconciled before: %d transactions\n", r.AlreadyRecorded)
+	}
+	sections := []struct {
+		title string
+		items []ReconciledItem
+	}{{"Matched", r.Matched}, {"Partially paid", r.Partial}}
+	for _, s := range sections {
+		fmt.Fprintf(out, "\n%s\n", s.title)
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(w, "date\tamount\tbooking_ref\tnet\tgross\treceived\toutstanding\t")
+		for _, item := range s.items {
+			b := item.Booking
+			fmt.Fprintf(w, "%s\t%.2f\t%s\t%.2f\t%.2f\t%.2f\t%.2f\t\n", item.Transaction.Date.Format(DateLayout),
+				item.Transaction.Amount, b.Form.BookingRef, b.Net, b.Retained, b.Received(), b.Outstanding())
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "\nUnmatched\n")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "date\tamount\tdescription\treason\t")
+	for _, u := range r.Unmatched {
+		fmt.Fprintf(w, "%s\t%.2f\t%s\t%s\t\n", u.Transaction.Date.Format(DateLayout), u.Transaction.Amount,
+			u.Transaction.Description, u.Reason)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\nDue but not paid\n")
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "due\tbooking_ref\tsource\tnet\tgross\treceived\toutstanding\t")
+	for _, b := range r.Unpaid {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.2f\t%.2f\t%.2f\t%.2f\t\n", b.DueDate.Format(DateLayout), b.Form.BookingRef,
+			b.Form.Source, b.Net, b.Retained, b.Received(), b.Outstanding())
+	}
+	return w.Flush()
+}
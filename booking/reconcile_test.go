@@ -0,0 +1,113 @@
+package booking
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReconcile(t *testing.T) {
+	store := NewMemoryStore()
+	for _, b := range testBookings() {
+		store.Create(b)
+	}
+	fb, _ := store.Get("6FBJUN1719")
+	ww, _ := store.Get("6WWJUL0108")
+	aug, _ := store.Get("6FBAUG0205")
+	transactions := []Transaction{
+		// the channel pays out the net, with the reference
+		{ID: "1", Date: Datetime(2017, time.May, 22), Amount: fb.Net, Description: "AIRBNB PAYOUT 6FBJUN1719"},
+		// the net, without a reference, within the window of its due date
+		{ID: "2", Date: Datetime(2017, time.May, 25), Amount: ww.Net, Description: "BOOKING.COM BV"},
+		// a deposit, with the reference
+		{ID: "3", Date: Datetime(2017, time.June, 1), Amount: 50, Description: "deposit 6fbaug0205 thanks"},
+		{ID: "4", Date: Datetime(2017, time.June, 2), Amount: 123.45, Description: "SOMEONE"},
+		{ID: "5", Date: Datetime(2017, time.June, 2), Amount: -20, Description: "BANK CHARGES"},
+		{ID: "6", Date: Datetime(2017, time.June, 3), Amount: 10, Description: "16FBJUN1719"},
+	}
+	r, err := Reconcile(store, transactions, DefaultReconcileWindow)
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if len(r.Matched) != 2 || r.Matched[0].Booking.Form.BookingRef != "6FBJUN1719" ||
+		r.Matched[1].Booking.Form.BookingRef != "6WWJUL0108" {
+		t.Errorf("Reconcile() matched = %+v", r.Matched)
+	}
+	if len(r.Partial) != 1 || r.Partial[0].Booking.Outstanding() != pence(aug.Net-50) {
+		t.Errorf("Reconcile() partial = %+v", r.Partial)
+	}
+	if len(r.Unmatched) != 2 || r.Unmatched[0].Transaction.ID != "4" || r.Unmatched[1].Transaction.ID != "6" {
+		t.Errorf("Reconcile() unmatched = %+v", r.Unmatched)
+	}
+	if len(r.Unpaid) != 1 || r.Unpaid[0].Form.BookingRef != "6FBAUG0205" {
+		t.Errorf("Reconcile() unpaid = %+v", r.Unpaid)
+	}
+	if b, _ := store.Get("6FBJUN1719"); !b.IsPaid() || len(b.Receipts) != 1 {
+		t.Errorf("6FBJUN1719 is not marked paid: %+v", b.Receipts)
+	}
+
+	// reconciling the same statement again records nothing twice
+	r, _ = Reconcile(store, transactions, DefaultReconcileWindow)
+	if r.AlreadyRecorded != 3 || len(r.Matched)+len(r.Partial) != 0 {
+		t.Errorf("Reconcile() again = %+v", r)
+	}
+	if b, _ := store.Get("6FBAUG0205"); b.Received() != 50 {
+		t.Errorf("6FBAUG0205 received = %v, want 50", b.Received())
+	}
+
+	// the rest of the gross pays off the booking
+	rest := Transaction{ID: "7", Date: Datetime(2017, time.June, 20), Amount: pence(aug.Retained - 50),
+		Description: "6FBAUG0205 balance"}
+	r, _ = Reconcile(store, []Transaction{rest}, DefaultReconcileWindow)
+	if len(r.Matched) != 1 || !r.Matched[0].Booking.IsPaid() {
+		t.Errorf("Reconcile() balance = %+v", r)
+	}
+	var buf bytes.Buffer
+	if err := WriteReconciliation(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "6FBAUG0205") {
+		t.Errorf("WriteReconciliation() does not show the match:\n%s", buf.String())
+	}
+}
+
+func TestReconcile_ambiguous(t *testing.T) {
+	store := NewMemoryStore()
+	for _, ref := range []string{"6FBJUN1719", "6WWJUN1719"} {
+		store.Create(CreateBooking(FormInput{BookingRef: ref, Source: Email, NumberOfPeople: 2,
+			Gross: 400, BookingDate: Datetime(2017, time.May, 20)}, testSettings))
+	}
+	r, _ := Reconcile(store, []Transaction{{ID: "1", Date: Datetime(2017, time.May, 21), Amount: 400}}, DefaultReconcileWindow)
+	if len(r.Unmatched) != 1 || !strings.Contains(r.Unmatched[0].Reason, "6FBJUN1719, 6WWJUN1719") {
+		t.Errorf("Reconcile() = %+v, want the payment unmatched as ambiguous", r)
+	}
+	// outside the window, it is for neither
+	r, _ = Reconcile(store, []Transaction{{ID: "2", Date: Datetime(2017, time.July, 1), Amount: 400}}, DefaultReconcileWindow)
+	if len(r.Unmatched) != 1 || r.Unmatched[0].Reason != "no booking owes this" {
+		t.Errorf("Reconcile() = %+v, want the payment unmatched", r)
+	}
+}
+
+func TestReconcile_identicalPayments(t *testing.T) {
+	store := NewMemoryStore()
+	store.Create(CreateBooking(FormInput{BookingRef: "6FBJUN1719", Source: Email, NumberOfPeople: 2,
+		Gross: 400, BookingDate: Datetime(2017, time.May, 20)}, testSettings))
+	// two equal payments on the same day, from a statement without ids
+	statement := "Date,Description,Amount\n2017-05-21,DEPOSIT 6FBJUN1719,100\n2017-05-21,DEPOSIT 6FBJUN1719,100\n"
+	transactions, err := ReadStatementCSV(strings.NewReader(statement))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := Reconcile(store, transactions, DefaultReconcileWindow)
+	if r.AlreadyRecorded != 0 || len(r.Partial) != 2 {
+		t.Errorf("Reconcile() = %+v, want both payments recorded", r)
+	}
+	if b, _ := store.Get("6FBJUN1719"); b.Received() != 200 {
+		t.Errorf("6FBJUN1719 received = %v, want 200", b.Received())
+	}
+	// and reconciling the statement again records neither twice
+	if r, _ = Reconcile(store, transactions, DefaultReconcileWindow); r.AlreadyRecorded != 2 {
+		t.Errorf("Reconcile() again recorded before = %v, want 2", r.AlreadyRecorded)
+	}
+}
@@ -0,0 +1,148 @@
+package booking
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+/*
+ * A booking reference is the year it was booked, the short name of the
+ * property, the month and day of arrival and the day of departure, e.g.
+ * 6FBJUN1719 for a stay at FB from the 17th to the 19th of June 2017, booked
+ * six years after the business opened. Each business configures how it
+ * writes its references, and without any configuration writes them so:
+ *
+ * "references": {
+ *   "opened" : "2011-01-01",
+ *   "prefix" : "",
+ *   "year" : "since_opening",
+ *   "months" : ["JAN","FEB","MAR","APR","MAY","JUN","JUL","AUG","SEP","OCT","NOV","DEC"]
+ * }
+ *
+ * The year is "since_opening", the years since the business opened, or
+ * "calendar", the year itself, e.g. 2017FBJUN1719. The prefix comes before
+ * every reference, e.g. "BA" gives BA6FBJUN1719. The months can be in any
+ * language, as long as they are all as long as each other, distinct, and
+ * without digits, e.g. ["JAN","FEV","MAR","AVR","MAI","JUN","JUL","AOU",...].
+ */
+
+// The ways a booking reference can give the year
+const (
+	YearsSinceOpening = "since_opening"
+	CalendarYear      = "calendar"
+)
+
+// defaultOpeningDate is when the business opened, unless it says otherwise
+var defaultOpeningDate = Datetime(2011, time.January, 1)
+
+// RefScheme configures how a business writes its booking references
+type RefScheme struct {
+	// Opened is the date the business opened, e.g. "2011-01-01"
+	Opened string `json:"opened"`
+	// Prefix comes before every reference
+	Prefix string `json:"prefix"`
+	// Year is YearsSinceOpening, which it defaults to, or CalendarYear
+	Year string `json:"year"`
+	// Months are the abbreviations of the months, January first
+	Months []string `json:"months"`
+}
+
+// OpeningDate returns the date the business opened
+func (s Settings) OpeningDate() time.Time {
+	return s.References.openingDate()
+}
+
+func (r RefScheme) openingDate() time.Time {
+	if r.Opened == "" {
+		return defaultOpeningDate
+	}
+	opened, err := ParseDate(r.Opened)
+	if err != nil {
+		return defaultOpeningDate
+	}
+	return opened
+}
+
+// yearNumber returns the number a reference gives for a year
+func (r RefScheme) yearNumber(year int) int {
+	if r.Year == CalendarYear {
+		return year
+	}
+	return year - r.openingDate().Year()
+}
+
+// year returns the year a number in a reference is for
+func (r RefScheme) year(number int) int {
+	if r.Year == CalendarYear {
+		return number
+	}
+	return yearsAfter(number, r.openingDate()).Year()
+}
+
+// monthName returns the abbreviation of month
+func (r RefScheme) monthName(month time.Month) string {
+	if len(r.Months) != nMonths {
+		return Month(month).String()
+	}
+	return r.Months[month-1]
+}
+
+// month returns the month an abbreviation is for
+func (r RefScheme) month(name string) (time.Month, bool) {
+	if len(r.Months) != nMonths {
+		m, ok := abbrevMonths[name]
+		return time.Month(m), ok
+	}
+	for i, m := range r.Months {
+		if m == name {
+			return time.Month(i + 1), true
+		}
+	}
+	return 0, false
+}
+
+// monthLen returns how long the abbreviations of the months are
+func (r RefScheme) monthLen() int {
+	if len(r.Months) != nMonths {
+		return 3
+	}
+	return len(r.Months[0])
+}
+
+// trimPrefix returns a reference without the prefix
+func (r RefScheme) trimPrefix(bookingRef string) string {
+	return strings.TrimPrefix(bookingRef, r.Prefix)
+}
+
+// validate checks the opening date can be read, the year is one of the ways
+// of giving it, and the months can be told apart in a reference
+func (r RefScheme) validate() error {
+	if r.Opened != "" {
+		if _, err := ParseDate(r.Opened); err != nil {
+			return fmt.Errorf("references: opened %q is not a date", r.Opened)
+		}
+	}
+	if r.Year != "" && r.Year != YearsSinceOpening && r.Year != CalendarYear {
+		return fmt.Errorf("references: unknown year %q", r.Year)
+	}
+	if r.Months == nil {
+		return nil
+	}
+	if len(r.Months) != nMonths {
+		return fmt.Errorf("references: %d months, want %d", len(r.Months), nMonths)
+	}
+	seen := make(map[string]bool)
+	for _, m := range r.Months {
+		switch {
+		case m == "" || len(m) != len(r.Months[0]):
+			return fmt.Errorf("references: months must all be as long as %q", r.Months[0])
+		case strings.ContainsAny(m, "0123456789"):
+			return fmt.Errorf("references: month %q has a digit", m)
+		case seen[m]:
+			return fmt.Errorf("references: month %q is there twice", m)
+		}
+		seen[m] = true
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package booking
+
+import (
+	"testing"
+	"time"
+)
+
+var frenchMonths = []string{"JAN", "FEV", "MAR", "AVR", "MAI", "JUN", "JUL", "AOU", "SEP", "OCT", "NOV", "DEC"}
+
+func TestRefScheme(t *testing.T) {
+	tests := []struct {
+		name string
+		refs RefScheme
+		want string
+	}{
+		{"default", RefScheme{}, "6FBAUG0205"},
+		{"opened later", RefScheme{Opened: "2015-04-01"}, "2FBAUG0205"},
+		{"calendar year", RefScheme{Year: CalendarYear}, "2017FBAUG0205"},
+		{"prefix", RefScheme{Prefix: "BA"}, "BA6FBAUG0205"},
+		{"french months", RefScheme{Prefix: "LR-", Year: CalendarYear, Months: frenchMonths}, "LR-2017FBAOU0205"},
+		{"long months", RefScheme{Months: []string{"JANV", "FEVR", "MARS", "AVRL", "MAI_", "JUIN",
+			"JUIL", "AOUT", "SEPT", "OCTO", "NOVE", "DECE"}}, "6FBAOUT0205"},
+	}
+	arrival, departure := Datetime(2017, time.August, 2), Datetime(2017, time.August, 5)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := testSettings
+			settings.References = tt.refs
+			if err := settings.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			ref, err := BookingRefFor(settings.Properties[0], arrival, departure, Datetime(2017, time.June, 1), settings)
+			if err != nil || ref != tt.want {
+				t.Fatalf("BookingRefFor() = %v, %v, want %v", ref, err, tt.want)
+			}
+			p, a, d, err := ParseBookingRef(ref, settings)
+			if err != nil || p.ShortName != "FB" || !a.Equal(arrival) || !d.Equal(departure) {
+				t.Errorf("ParseBookingRef(%q) = %s %v %v, %v", ref, p.ShortName, a, d, err)
+			}
+			b := CreateBooking(FormInput{BookingRef: ref, NumberOfPeople: 1}, settings)
+			if !b.Arrival.Equal(arrival) || !b.Departure.Equal(departure) {
+				t.Errorf("CreateBooking(%q) stays %v to %v", ref, b.Arrival, b.Departure)
+			}
+		})
+	}
+	// a reference without the prefix is not one of the business's
+	settings := testSettings
+	settings.References = RefScheme{Prefix: "BA"}
+	if _, _, _, err := ParseBookingRef("6FBAUG0205", settings); err == nil {
+		t.Errorf("ParseBookingRef() without the prefix did not fail")
+	}
+}
+
+func TestRefSchemeValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		refs    RefScheme
+		wantErr bool
+	}{
+		{"default", RefScheme{}, false},
+		{"bad opening date", RefScheme{Opened: "1st January 2011"}, true},
+		{"unknown year", RefScheme{Year: "fiscal"}, true},
+		{"eleven months", RefScheme{Months: frenchMonths[:11]}, true},
+		{"uneven months", RefScheme{Months: append([]string{"JANV"}, frenchMonths[1:]...)}, true},
+		{"month with digit", RefScheme{Months: append([]string{"JA1"}, frenchMonths[1:]...)}, true},
+		{"month twice", RefScheme{Months: append([]string{"FEV"}, frenchMonths[1:]...)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (Settings{References: tt.refs}).Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package booking
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// OwnerReport is a statement for the owner of one property, covering the
+// bookings arriving in a period
+type OwnerReport struct {
+	Property    Property
+	From        time.Time
+	To          time.Time
+	Bookings    []Booking
+	Gross       float64
+	Net         float64
+	TotalFees   float64
+	OwnerIncome float64
+	// OmitPII leaves guests' names out of the written report
+	OmitPII bool
+}
+
+// BuildOwnerReports returns a report for every property in settings, from the
+// bookings in the store arriving on or after from and before to
+func BuildOwnerReports(store BookingStore, settings Settings, from, to time.Time) ([]OwnerReport, error) {
+	var reports []OwnerReport
+	for _, p := range settings.Properties {
+		bookings, err := store.List(BookingFilter{Property: p.ShortName, From: from, To: to})
+		if err != nil {
+			return nil, err
+		}
+		r := OwnerReport{Property: p, From: from, To: to, Bookings: bookings}
+		for _, b := range bookings {
+			r.Gross += b.Form.Gross
+			r.Net += b.Net
+			r.TotalFees += b.TotalFees
+			r.OwnerIncome += b.OwnerIncome
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// WriteOwnerReport writes a report as an aligned plain text table
+func WriteOwnerReport(out io.Writer, r OwnerReport) error {
+	fmt.Fprintf(out, "%s (%s)\n", r.Property.LongName, r.Property.ShortName)
+	fmt.Fprintf(out, "Arrivals from %s to %s\n\n", r.From.Format(DateLayout), r.To.Format(DateLayout))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	guest := "guest\t"
+	if r.OmitPII {
+		guest = ""
+	}
+	fmt.Fprintln(w, "booking_ref\t"+guest+"arrival\tdeparture\tgross\tnet\ttotal_fees\towner_income\t")
+	for _, b := range r.Bookings {
+		if !r.OmitPII {
+			guest = b.Form.FirstName + " " + b.Form.LastName + "\t"
+		}
+		fmt.Fprintf(w, "%s\t%s%s\t%s\t%.2f\t%.2f\t%.2f\t%.2f\t\n",
+			b.Form.BookingRef, guest,
+			b.Arrival.Format(DateLayout), b.Departure.Format(DateLayout),
+			b.Form.Gross, b.Net, b.TotalFees, b.OwnerIncome)
+	}
+	if !r.OmitPII {
+		guest = "\t"
+	}
+	fmt.Fprintf(w, "total\t%s\t\t%.2f\t%.2f\t%.2f\t%.2f\t\n", guest, r.Gross, r.Net, r.TotalFees, r.OwnerIncome)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(out)
+	return err
+}
+
+// TaxReturnLine totals one tax in one jurisdiction over a quarter
+type TaxReturnLine struct {
+	Jurisdiction string
+	Name         string
+	Type         string
+	Bookings     int
+	Base         float64
+	Amount       float64
+}
+
+// TaxReturn summarises the taxes levied on the bookings arriving in a quarter
+type TaxReturn struct {
+	Year    int
+	Quarter int
+	From    time.Time
+	To      time.Time
+	Lines   []TaxReturnLine
+}
+
+// quarterDates returns the first day of a quarter, and the first day after it
+func quarterDates(year, quarter int) (time.Time, time.Time) {
+	from := Datetime(year, time.Month(3*(quarter-1)+1), 1)
+	return from, from.AddDate(0, 3, 0)
+}
+
+// BuildTaxReturn totals the taxes on the bookings in the store arriving in
+// quarter 1 to 4 of year, per jurisdiction and tax
+func BuildTaxReturn(store BookingStore, year, quarter int) (TaxReturn, error) {
+	if quarter < 1 || quarter > 4 {
+		return TaxReturn{}, fmt.Errorf("no quarter %d", quarter)
+	}
+	from, to := quarterDates(year, quarter)
+	r := TaxReturn{Year: year, Quarter: quarter, From: from, To: to}
+	bookings, err := store.List(BookingFilter{From: from, To: to})
+	if err != nil {
+		return r, err
+	}
+	index := make(map[[2]string]int)
+	for _, b := range bookings {
+		for _, t := range b.Taxes {
+			key := [2]string{t.Jurisdiction, t.Name}
+			i, ok := index[key]
+			if !ok {
+				i = len(r.Lines)
+				index[key] = i
+				r.Lines = append(r.Lines, TaxReturnLine{Jurisdiction: t.Jurisdiction, Name: t.Name, Type: t.Type})
+			}
+			r.Lines[i].Bookings++
+			r.Lines[i].Base += t.Base
+			r.Lines[i].Amount += t.Amount
+		}
+	}
+	sort.Slice(r.Lines, func(i, j int) bool {
+		if r.Lines[i].Jurisdiction != r.Lines[j].Jurisdiction {
+			return r.Lines[i].Jurisdiction < r.Lines[j].Jurisdiction
+		}
+		return r.Lines[i].Name < r.Lines[j].Name
+	})
+	return r, nil
+}
+
+// WriteTaxReturn writes a tax return as an aligned plain text table
+func WriteTaxReturn(out io.Writer, r TaxReturn) error {
+	fmt.Fprintf(out, "Tax return for Q%d %d\n", r.Quarter, r.Year)
+	fmt.Fprintf(out, "Arrivals from %s to %s\n\n", r.From.Format(DateLayout), r.To.Format(DateLayout))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "jurisdiction\ttax\ttype\tbookings\tbase\tamount\t")
+	total := 0.0
+	for _, l := range r.Lines {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%.2f\t%.2f\t\n", l.Jurisdiction, l.Name, l.Type, l.Bookings, l.Base, l.Amount)
+		total += l.Amount
+	}
+	fmt.Fprintf(w, "total\t\t\t\t\t%.2f\t\n", total)
+	return w.Flush()
+}
+
+// WriteGuestHistories writes a summary of each guest's history as an aligned
+// plain text table
+func WriteGuestHistories(out io.Writer, histories []GuestHistory) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "guest_id\tguest\temail\tmobile\tstays\tnights\tspend\tproperties\t")
+	for _, h := range histories {
+		g := h.Guest
+		fmt.Fprintf(w, "%s\t%s %s\t%s\t%s\t%d\t%d\t%.2f\t%s\t\n", g.ID, g.FirstName, g.LastName,
+			g.Email, g.Mobile, h.Stays, h.Nights, h.Spend, strings.Join(h.Properties, ","))
+	}
+	return w.Flush()
+}
+
+// WriteGuestHistory writes one guest's bookings as an aligned plain text table
+func WriteGuestHistory(out io.Writer, h GuestHistory) error {
+	g := h.Guest
+	fmt.Fprintf(out, "%s %s (%s)\n%s %s\n\n", g.FirstName, g.LastName, g.ID, g.Email, g.Mobile)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "booking_ref\tproperty\tarrival\tdeparture\tstatus\tspend\t")
+	for _, b := range h.Bookings {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%.2f\t\n", b.Form.BookingRef, b.Property.ShortName,
+			b.Arrival.Format(DateLayout), b.Departure.Format(DateLayout), b.Form.Status, b.Retained)
+	}
+	fmt.Fprintf(w, "total\t\t\t\t%d stays\t%.2f\t\n", h.Stays, h.Spend)
+	return w.Flush()
+}
@@ -0,0 +1,55 @@
+package booking
+
+/*
+ * The settlement model works out what the house owner is paid for a booking.
+ * Starting from the gross the guest paid (less any discount and refund),
+ * deductions are taken in this order:
+ *
+ *   1. the channel fee, at BookingCommission (15% on booking.com)
+ *   2. the agency commission, at Commission, unless the booking is owner-direct
+ *      or from a commission free source
+ *   3. the house owner commission, at HouseOwnerCommission
+ *
+ * Each deduction is a rate of either the gross, or the net: what is left of
+ * the gross after the deductions before it. A deduction is never less than its
+ * minimum, except on cancelled and no-show bookings. The services provided
+ * are then charged, and what remains is the owner's income.
+ *
+ * These deductions and services are the default fee rules of a property (see
+ * feerules.go), which a property with "fee_rules" replaces with its own.
+ * A property configures the default settlement like so, and without one uses
+ * defaultSettlement:
+ *
+ * "settlement": {
+ *   "channel_fee" : { "basis" : "gross" },
+ *   "agency_commission" : { "basis" : "net" },
+ *   "house_owner_commission" : { "basis" : "net", "minimum" : 35 }
+ * }
+ */
+
+// Deduction configures one step of the settlement
+type Deduction struct {
+	// Basis is "gross" or "net"
+	Basis   string  `json:"basis"`
+	Minimum float64 `json:"minimum"`
+}
+
+// Settlement configures the deductions taken from a booking's gross
+type Settlement struct {
+	ChannelFee           Deduction `json:"channel_fee"`
+	AgencyCommission     Deduction `json:"agency_commission"`
+	HouseOwnerCommission Deduction `json:"house_owner_commission"`
+}
+
+var defaultSettlement = Settlement{
+	ChannelFee:           Deduction{Basis: "gross"},
+	AgencyCommission:     Deduction{Basis: "net"},
+	HouseOwnerCommission: Deduction{Basis: "net", Minimum: 35},
+}
+
+func (p Property) settlement() Settlement {
+	if p.Settlement == nil {
+		return defaultSettlement
+	}
+	return *p.Settlement
+}
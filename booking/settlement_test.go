@@ -0,0 +1,95 @@
+package booking
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestProperty_defaultFeeRules(t *testing.T) {
+	p := Property{BookingCommission: 0.1, Commission: 0.2, HouseOwnerCommission: 0.1}
+	tests := []struct {
+		name       string
+		settlement *Settlement
+		f          FormInput
+		want       []LineItem
+	}{
+		{"default", nil, FormInput{Source: Email}, []LineItem{
+			{Name: "channel_fee", Kind: ChannelCommission, Rate: 0.1, Base: 1000, Amount: 100},
+			{Name: "agency_commission", Kind: AgencyCommission, Rate: 0.2, Base: 900, Amount: 180},
+			{Name: "house_owner_commission", Kind: HouseOwnerCommission, Rate: 0.1, Base: 720, Amount: 72},
+		}},
+		{"booking.com", nil, FormInput{Source: BookingCom}, []LineItem{
+			{Name: "channel_fee", Kind: ChannelCommission, Rate: 0.15, Base: 1000, Amount: 150},
+			{Name: "agency_commission", Kind: AgencyCommission, Rate: 0.2, Base: 850, Amount: 170},
+			{Name: "house_owner_commission", Kind: HouseOwnerCommission, Rate: 0.1, Base: 680, Amount: 68},
+		}},
+		{"owner direct", nil, FormInput{Source: Email, IsOwnerDirect: true}, []LineItem{
+			{Name: "channel_fee", Kind: ChannelCommission, Rate: 0.1, Base: 1000, Amount: 100},
+			{Name: "house_owner_commission", Kind: HouseOwnerCommission, Rate: 0.1, Base: 900, Amount: 90},
+		}},
+		{"gross basis and minimums", &Settlement{
+			AgencyCommission:     Deduction{Basis: "gross"},
+			HouseOwnerCommission: Deduction{Basis: "gross", Minimum: 150},
+		}, FormInput{Source: Email}, []LineItem{
+			{Name: "channel_fee", Kind: ChannelCommission, Rate: 0.1, Base: 1000, Amount: 100},
+			{Name: "agency_commission", Kind: AgencyCommission, Rate: 0.2, Base: 1000, Amount: 200},
+			{Name: "house_owner_commission", Kind: HouseOwnerCommission, Rate: 0.1, Base: 1000, Amount: 150},
+		}},
+		{"no minimum when cancelled", &Settlement{
+			HouseOwnerCommission: Deduction{Basis: "gross", Minimum: 150},
+		}, FormInput{Source: Email, Status: Cancelled}, []LineItem{
+			{Name: "channel_fee", Kind: ChannelCommission, Rate: 0.1, Base: 1000, Amount: 100},
+			{Name: "agency_commission", Kind: AgencyCommission, Rate: 0.2, Base: 900, Amount: 180},
+			{Name: "house_owner_commission", Kind: HouseOwnerCommission, Rate: 0.1, Base: 1000, Amount: 100},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := p
+			p.Settlement = tt.settlement
+			var got []LineItem
+			for _, item := range ApplyFeeRules(p.defaultFeeRules(), &FeeContext{Form: tt.f, Property: p, Gross: 1000, Net: 1000}) {
+				if item.Kind != PerStay {
+					got = append(got, item)
+				}
+			}
+			for i := range got {
+				got[i].Amount = math.Round(got[i].Amount*100) / 100
+				got[i].Base = math.Round(got[i].Base*100) / 100
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commissions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_createBooking_settlement(t *testing.T) {
+	f := FormInput{BookingRef: "6FBJUN1719", Source: AirBnb, NumberOfPeople: 2, Gross: 1000,
+		IsGreeting: true, IsLaundry: true, IsCleaning: true, IsConsumables: true}
+	b := CreateBooking(f, testSettings)
+	// FB has no booking commission, 10% agency and 10% house owner commission
+	if b.AgencyCommission != 100 {
+		t.Errorf("CreateBooking() agency commission = %v, want 100", b.AgencyCommission)
+	}
+	if math.Abs(b.HouseOwnerFee-90) > 1e-9 {
+		t.Errorf("CreateBooking() house owner fee = %v, want 90", b.HouseOwnerFee)
+	}
+	// 100 + 90 commission, plus 15 + 10 + 35 + 15 services
+	if math.Abs(b.TotalFees-265) > 1e-9 {
+		t.Errorf("CreateBooking() total fees = %v, want 265", b.TotalFees)
+	}
+	if b.OwnerIncome != b.Net-b.TotalFees {
+		t.Errorf("CreateBooking() owner income = %v, want %v", b.OwnerIncome, b.Net-b.TotalFees)
+	}
+	var names []string
+	for _, item := range b.LineItems {
+		names = append(names, item.Name)
+	}
+	want := []string{"channel_fee", "agency_commission", "house_owner_commission",
+		"consumables", "laundry", "greeting", "cleaning"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("CreateBooking() line items = %v, want %v", names, want)
+	}
+}
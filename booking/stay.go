@@ -0,0 +1,59 @@
+package booking
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+/*
+ * Services can cost more or less on longer stays. A property adjusts the
+ * total of its services by the adjustment for the longest minimum stay that a
+ * booking meets, e.g. 10% off services for stays of a fortnight or more:
+ *
+ * "length_of_stay": [ { "min_nights" : 14, "adjustment" : -0.1 } ]
+ */
+
+// NightsBetween returns how many nights a stay from arrival to departure lasts
+func NightsBetween(arrival, departure time.Time) int {
+	return int(math.Round(departure.Sub(arrival).Hours() / 24))
+}
+
+// StayAdjustment changes the price of services by a fraction for stays of at
+// least MinNights. It is negative for a discount and positive for a surcharge.
+type StayAdjustment struct {
+	MinNights  int     `json:"min_nights"`
+	Adjustment float64 `json:"adjustment"`
+}
+
+// LengthOfStayRule adjusts the services charged by the rules before it
+type LengthOfStayRule struct {
+	Name        string
+	Adjustments []StayAdjustment
+}
+
+// Apply adds a line item adjusting the services so far, if the stay is long enough
+func (r LengthOfStayRule) Apply(ctx *FeeContext) []LineItem {
+	adjustments := append([]StayAdjustment(nil), r.Adjustments...)
+	sort.Slice(adjustments, func(i, j int) bool {
+		return adjustments[i].MinNights > adjustments[j].MinNights
+	})
+	nights := ctx.nights()
+	for _, a := range adjustments {
+		if nights < a.MinNights {
+			continue
+		}
+		services := 0.0
+		for _, item := range ctx.Items {
+			if isServiceKind(item.Kind) {
+				services += item.Amount
+			}
+		}
+		if services == 0 || a.Adjustment == 0 {
+			return nil
+		}
+		return []LineItem{{Name: r.Name, Kind: LengthOfStay, Rate: a.Adjustment, Base: services,
+			Amount: a.Adjustment * services}}
+	}
+	return nil
+}
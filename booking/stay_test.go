@@ -0,0 +1,82 @@
+package booking
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_nightsBetween(t *testing.T) {
+	tests := []struct {
+		name      string
+		arrival   time.Time
+		departure time.Time
+		want      int
+	}{
+		{"weekend", Datetime(2017, time.June, 17), Datetime(2017, time.June, 19), 2},
+		{"over new year", Datetime(2017, time.December, 31), Datetime(2018, time.January, 3), 3},
+		{"same day", Datetime(2017, time.June, 17), Datetime(2017, time.June, 17), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NightsBetween(tt.arrival, tt.departure); got != tt.want {
+				t.Errorf("NightsBetween() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_createBooking_lengthOfStay(t *testing.T) {
+	settings := Settings{Properties: []Property{{ShortName: "FB", Cleaning: 35,
+		Laundry: []float64{10}, PerNight: map[string]float64{"consumables": 2},
+		MidStayCleanEvery: 7,
+		LengthOfStay: []StayAdjustment{
+			{MinNights: 7, Adjustment: -0.05},
+			{MinNights: 14, Adjustment: -0.1},
+		}}}}
+	f := FormInput{NumberOfPeople: 2, IsCleaning: true, IsLaundry: true, IsConsumables: true}
+	tests := []struct {
+		name         string
+		ref          string
+		wantMidStay  float64
+		wantServices float64
+	}{
+		// 35 cleaning + 10 laundry + 2 a night
+		{"two nights", "6FBJUN0103", 0, 49},
+		// a week has no mid-stay clean, as the clean is on departure
+		{"one week", "6FBJUN0108", 0, (45 + 14) * 0.95},
+		{"eight nights", "6FBJUN0109", 35, (45 + 16 + 35) * 0.95},
+		// a fortnight has one mid-stay clean, at the end of the first week
+		{"fortnight", "6FBJUN0115", 35, (45 + 28 + 35) * 0.9},
+		{"three weeks", "6FBJUN0122", 70, (45 + 42 + 70) * 0.9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := f
+			f.BookingRef = tt.ref
+			b := CreateBooking(f, settings)
+			if got := LineItemTotal(b.LineItems, MidStay); got != tt.wantMidStay {
+				t.Errorf("CreateBooking() mid-stay cleaning = %v, want %v", got, tt.wantMidStay)
+			}
+			row := BookingSpreadsheetRow(b)
+			services := 0.0
+			for _, item := range row.Services {
+				services += item.Amount
+			}
+			if math.Abs(services-tt.wantServices) > 1e-9 {
+				t.Errorf("spreadsheet row services = %v, want %v", services, tt.wantServices)
+			}
+		})
+	}
+}
+
+func Test_formatLineItems(t *testing.T) {
+	items := []LineItem{{Name: "cleaning", Amount: 35}, {Name: "length_of_stay", Amount: -3.5}}
+	if got, want := formatLineItems(items), "cleaning=35.00;length_of_stay=-3.50"; got != want {
+		t.Errorf("formatLineItems() = %v, want %v", got, want)
+	}
+	if got := parseExtras(formatExtras(map[string]int{"pets": 2, "cots": 1})); !reflect.DeepEqual(got, map[string]int{"pets": 2, "cots": 1}) {
+		t.Errorf("parseExtras(formatExtras()) = %v", got)
+	}
+}
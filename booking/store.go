@@ -0,0 +1,232 @@
+package booking
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrBookingNotFound is returned when no booking has the given reference
+var ErrBookingNotFound = errors.New("booking not found")
+
+// ErrBookingExists is returned when creating a booking whose reference is taken
+var ErrBookingExists = errors.New("booking already exists")
+
+// BookingFilter narrows down the bookings returned by List.
+// Zero-valued fields match every booking.
+type BookingFilter struct {
+	// Property is the short name of the property
+	Property string
+	// From and To bound the arrival date: From inclusive, To exclusive
+	From time.Time
+	To   time.Time
+	// Source is where the booking originated from
+	Source Source
+}
+
+func (filter BookingFilter) matches(b Booking) bool {
+	if filter.Property != "" && b.Property.ShortName != filter.Property {
+		return false
+	}
+	if !filter.From.IsZero() && b.Arrival.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && !b.Arrival.Before(filter.To) {
+		return false
+	}
+	if filter.Source != 0 && b.Form.Source != filter.Source {
+		return false
+	}
+	return true
+}
+
+// BookingStore keeps bookings, keyed by their booking reference
+type BookingStore interface {
+	Create(b Booking) error
+	Get(ref string) (Booking, error)
+	List(filter BookingFilter) ([]Booking, error)
+	Update(b Booking) error
+	Delete(ref string) error
+}
+
+// MemoryStore is a BookingStore that lives only as long as the process
+type MemoryStore struct {
+	mu       sync.RWMutex
+	bookings map[string]Booking
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{bookings: make(map[string]Booking)}
+}
+
+// Create adds a new booking
+func (s *MemoryStore) Create(b Booking) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.bookings[b.Form.BookingRef]; ok {
+		return ErrBookingExists
+	}
+	s.bookings[b.Form.BookingRef] = b
+	return nil
+}
+
+// Get returns the booking with the given reference
+func (s *MemoryStore) Get(ref string) (Booking, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.bookings[ref]
+	if !ok {
+		return Booking{}, ErrBookingNotFound
+	}
+	return b, nil
+}
+
+// List returns the bookings matching filter, ordered by arrival date then reference
+func (s *MemoryStore) List(filter BookingFilter) ([]Booking, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var bookings []Booking
+	for _, b := range s.bookings {
+		if filter.matches(b) {
+			bookings = append(bookings, b)
+		}
+	}
+	sort.Slice(bookings, func(i, j int) bool {
+		if !bookings[i].Arrival.Equal(bookings[j].Arrival) {
+			return bookings[i].Arrival.Before(bookings[j].Arrival)
+		}
+		return bookings[i].Form.BookingRef < bookings[j].Form.BookingRef
+	})
+	return bookings, nil
+}
+
+// Update replaces an existing booking
+func (s *MemoryStore) Update(b Booking) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.bookings[b.Form.BookingRef]; !ok {
+		return ErrBookingNotFound
+	}
+	s.bookings[b.Form.BookingRef] = b
+	return nil
+}
+
+// Delete removes a booking
+func (s *MemoryStore) Delete(ref string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.bookings[ref]; !ok {
+		return ErrBookingNotFound
+	}
+	delete(s.bookings, ref)
+	return nil
+}
+
+// FileStore is a BookingStore kept in a JSON-lines file, one booking per line.
+// The whole file is read when opened and rewritten after every change.
+type FileStore struct {
+	path  string
+	mu    sync.Mutex
+	store *MemoryStore
+}
+
+// OpenFileStore opens the store at path, which is created on first write if
+// it does not exist yet
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path, store: NewMemoryStore()}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var b Booking
+		if err := json.Unmarshal(scanner.Bytes(), &b); err != nil {
+			return nil, err
+		}
+		s.store.bookings[b.Form.BookingRef] = b
+	}
+	return s, scanner.Err()
+}
+
+// save writes every booking to a temporary file and moves it over the store,
+// so a failed write never leaves a half-written store behind
+func (s *FileStore) save() error {
+	bookings, _ := s.store.List(BookingFilter{})
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, b := range bookings {
+		if err = enc.Encode(b); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Create adds a new booking
+func (s *FileStore) Create(b Booking) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.store.Create(b); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+// Get returns the booking with the given reference
+func (s *FileStore) Get(ref string) (Booking, error) {
+	return s.store.Get(ref)
+}
+
+// List returns the bookings matching filter, ordered by arrival date then reference
+func (s *FileStore) List(filter BookingFilter) ([]Booking, error) {
+	return s.store.List(filter)
+}
+
+// Update replaces an existing booking
+func (s *FileStore) Update(b Booking) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.store.Update(b); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+// Delete removes a booking
+func (s *FileStore) Delete(ref string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.store.Delete(ref); err != nil {
+		return err
+	}
+	return s.save()
+}
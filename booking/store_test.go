@@ -0,0 +1,145 @@
+package booking
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testSettings = Settings{Properties: []Property{
+	{LongName: "FooBarBaz", ShortName: "FB", Commission: 0.1, HouseOwnerCommission: 0.1,
+		Greeting: 15, Laundry: []float64{10, 10, 15, 15, 25, 25}, Cleaning: 35,
+		Consumables: []float64{15, 15, 25, 25, 35, 35}},
+	{LongName: "WibbleWobbleWoo", ShortName: "WW", Commission: 0.2, BookingCommission: 0.1,
+		HouseOwnerCommission: 0.3, Greeting: 25, Laundry: []float64{15, 15, 20, 20, 35, 35},
+		Cleaning: 35, Consumables: []float64{15, 15, 25, 25, 35, 35}},
+}}
+
+func testBookings() []Booking {
+	return []Booking{
+		CreateBooking(FormInput{BookingRef: "6FBJUN1719", Source: AirBnb, NumberOfPeople: 2,
+			Gross: 400, BookingDate: Datetime(2017, time.May, 20),
+			IsGreeting: true, IsLaundry: true, IsCleaning: true, IsConsumables: true}, testSettings),
+		CreateBooking(FormInput{BookingRef: "6WWJUL0108", Source: BookingCom, NumberOfPeople: 4,
+			Gross: 900, BookingDate: Datetime(2017, time.May, 21),
+			IsGreeting: true, IsLaundry: true, IsCleaning: true, IsConsumables: true}, testSettings),
+		CreateBooking(FormInput{BookingRef: "6FBAUG0205", Source: Email, NumberOfPeople: 1,
+			Gross: 250, BookingDate: Datetime(2017, time.June, 1),
+			IsGreeting: true, IsLaundry: true, IsCleaning: true, IsConsumables: true}, testSettings),
+	}
+}
+
+func refs(bookings []Booking) []string {
+	var refs []string
+	for _, b := range bookings {
+		refs = append(refs, b.Form.BookingRef)
+	}
+	return refs
+}
+
+func testBookingStore(t *testing.T, store BookingStore) {
+	for _, b := range testBookings() {
+		if err := store.Create(b); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	if err := store.Create(testBookings()[0]); err != ErrBookingExists {
+		t.Errorf("Create() duplicate error = %v, want %v", err, ErrBookingExists)
+	}
+
+	tests := []struct {
+		name   string
+		filter BookingFilter
+		want   []string
+	}{
+		{"all", BookingFilter{}, []string{"6FBJUN1719", "6WWJUL0108", "6FBAUG0205"}},
+		{"property", BookingFilter{Property: "FB"}, []string{"6FBJUN1719", "6FBAUG0205"}},
+		{"source", BookingFilter{Source: BookingCom}, []string{"6WWJUL0108"}},
+		{"from", BookingFilter{From: Datetime(2017, time.July, 1)}, []string{"6WWJUL0108", "6FBAUG0205"}},
+		{"to", BookingFilter{To: Datetime(2017, time.July, 1)}, []string{"6FBJUN1719"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.List(tt.filter)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if !reflect.DeepEqual(refs(got), tt.want) {
+				t.Errorf("List() = %v, want %v", refs(got), tt.want)
+			}
+		})
+	}
+
+	b, err := store.Get("6WWJUL0108")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	b.Form.Notes = "late arrival"
+	if err := store.Update(b); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if b, _ = store.Get("6WWJUL0108"); b.Form.Notes != "late arrival" {
+		t.Errorf("Get() after Update() notes = %q", b.Form.Notes)
+	}
+	if err := store.Delete("6WWJUL0108"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get("6WWJUL0108"); err != ErrBookingNotFound {
+		t.Errorf("Get() after Delete() error = %v, want %v", err, ErrBookingNotFound)
+	}
+	if err := store.Update(b); err != ErrBookingNotFound {
+		t.Errorf("Update() after Delete() error = %v, want %v", err, ErrBookingNotFound)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testBookingStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookings.jsonl")
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	testBookingStore(t, store)
+
+	reopened, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() reopening error = %v", err)
+	}
+	got, _ := reopened.List(BookingFilter{})
+	want, _ := store.List(BookingFilter{})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("reopened store = %v, want %v", refs(got), refs(want))
+	}
+}
+
+func TestImportExportCSV(t *testing.T) {
+	dir := t.TempDir()
+	store := NewMemoryStore()
+	for _, b := range testBookings() {
+		store.Create(b)
+	}
+	out := filepath.Join(dir, "out.csv")
+	if err := ExportCSV(out, store, BookingFilter{}); err != nil {
+		t.Fatalf("ExportCSV() error = %v", err)
+	}
+	imported := NewMemoryStore()
+	report, err := ImportCSV(out, testSettings, imported)
+	if err != nil {
+		t.Fatalf("ImportCSV() error = %v", err)
+	}
+	if report.Imported != 3 || len(report.Rejected) != 0 {
+		t.Errorf("ImportCSV() = %+v, want 3 imported", report)
+	}
+	got, _ := imported.List(BookingFilter{})
+	want, _ := store.List(BookingFilter{})
+	if !reflect.DeepEqual(refs(got), refs(want)) {
+		t.Errorf("imported %v, want %v", refs(got), refs(want))
+	}
+	if got[0].OwnerIncome != want[0].OwnerIncome {
+		t.Errorf("imported owner income = %v, want %v", got[0].OwnerIncome, want[0].OwnerIncome)
+	}
+}
@@ -0,0 +1,148 @@
+package booking
+
+import "fmt"
+
+/*
+ * Each property lists the taxes levied on its bookings, e.g. a tourist tax of
+ * 2 a night for each adult, on at most 7 nights, and VAT on services:
+ *
+ * "taxes": [
+ *   { "name" : "tourist_tax", "jurisdiction" : "Bath", "type" : "per_person_per_night",
+ *     "rate" : 2, "exempt_children" : true, "max_nights" : 7 },
+ *   { "name" : "vat", "jurisdiction" : "UK", "type" : "vat", "rate" : 0.2,
+ *     "services" : [ "cleaning", "laundry" ] }
+ * ]
+ *
+ * Taxes are only levied on stays that take place. They are collected on top of
+ * what is charged, so they are recorded alongside a booking's fees rather than
+ * taken out of the owner's income.
+ */
+
+// The types of tax
+const (
+	// PerPersonPerNight taxes each guest for each night they stay
+	PerPersonPerNight = "per_person_per_night"
+	// PercentOfGross taxes a fraction of what the guest pays
+	PercentOfGross = "percent_of_gross"
+	// VAT taxes a fraction of the services charged for
+	VAT = "vat"
+)
+
+// TaxRule configures one tax levied on a property's bookings
+type TaxRule struct {
+	Name         string  `json:"name"`
+	Jurisdiction string  `json:"jurisdiction"`
+	Type         string  `json:"type"`
+	Rate         float64 `json:"rate"`
+	// ExemptChildren leaves children out of a PerPersonPerNight tax
+	ExemptChildren bool `json:"exempt_children"`
+	// MaxNights caps the nights a PerPersonPerNight tax is levied on, if set
+	MaxNights int `json:"max_nights"`
+	// Services are the services VAT is levied on, or all of them if empty.
+	// A service is matched however it is charged for, e.g. "cleaning" taxes
+	// the "cleaning", "cleaning_per_night" and "mid_stay_cleaning" items, as
+	// well as its share of any length of stay adjustment. Fee rules without
+	// a service are matched by name, e.g. "heating".
+	Services []string `json:"services"`
+}
+
+// TaxLine is one tax levied on a booking
+type TaxLine struct {
+	Name         string
+	Jurisdiction string
+	Type         string
+	// Rate times Base is Amount: a charge and how many person-nights it is
+	// levied on, or a fraction and the sum it is levied on
+	Rate   float64
+	Base   float64
+	Amount float64
+}
+
+func (r TaxRule) validate() error {
+	switch r.Type {
+	case PerPersonPerNight, PercentOfGross, VAT:
+		return nil
+	}
+	return fmt.Errorf("tax %q: unknown type %q", r.Name, r.Type)
+}
+
+// taxesService reports whether VAT is levied on the service line item, by
+// the service it charges for or the name of the rule that made it
+func (r TaxRule) taxesService(item LineItem) bool {
+	if !isServiceKind(item.Kind) || item.Kind == LengthOfStay {
+		return false
+	}
+	if len(r.Services) == 0 {
+		return true
+	}
+	for _, s := range r.Services {
+		if s == item.Service || s == item.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// vatBase returns what VAT is levied on among the line items: the services
+// it taxes, adjusted by their share of any length of stay adjustment
+func (r TaxRule) vatBase(items []LineItem) float64 {
+	base := 0.0
+	for _, item := range items {
+		switch {
+		case r.taxesService(item):
+			base += item.Amount
+		case item.Kind == LengthOfStay && item.Base != 0:
+			// the adjustment is a rate of every service before it
+			base += item.Rate * base
+		}
+	}
+	return base
+}
+
+// taxLines levies the property's taxes on a booking, given what the guest
+// paid and the line items of its fees
+func (p Property) taxLines(f FormInput, nights int, paid float64, items []LineItem) []TaxLine {
+	if !f.Status.takesPlace() {
+		return nil
+	}
+	var lines []TaxLine
+	for _, r := range p.Taxes {
+		var base float64
+		switch r.Type {
+		case PerPersonPerNight:
+			people := f.NumberOfPeople
+			if r.ExemptChildren {
+				people -= f.NumberOfChildren
+			}
+			taxedNights := nights
+			if r.MaxNights > 0 && taxedNights > r.MaxNights {
+				taxedNights = r.MaxNights
+			}
+			base = float64(people * taxedNights)
+		case PercentOfGross:
+			base = paid
+		case VAT:
+			base = r.vatBase(items)
+		default:
+			continue
+		}
+		if base <= 0 {
+			continue
+		}
+		lines = append(lines, TaxLine{Name: r.Name, Jurisdiction: r.Jurisdiction, Type: r.Type,
+			Rate: r.Rate, Base: base, Amount: r.Rate * base})
+	}
+	return lines
+}
+
+// TaxTotal returns the total of the VAT lines if vat is true, else of the
+// other, tourist tax, lines
+func TaxTotal(lines []TaxLine, vat bool) float64 {
+	total := 0.0
+	for _, l := range lines {
+		if (l.Type == VAT) == vat {
+			total += l.Amount
+		}
+	}
+	return total
+}
@@ -0,0 +1,109 @@
+package booking
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var taxSettings = Settings{Properties: []Property{{ShortName: "FB", Cleaning: 35, Laundry: []float64{10},
+	Taxes: []TaxRule{
+		{Name: "tourist_tax", Jurisdiction: "Bath", Type: PerPersonPerNight, Rate: 2, ExemptChildren: true, MaxNights: 7},
+		{Name: "occupancy_tax", Jurisdiction: "Bath", Type: PercentOfGross, Rate: 0.05},
+		{Name: "vat", Jurisdiction: "UK", Type: VAT, Rate: 0.2, Services: []string{"cleaning"}},
+	}}}}
+
+func Test_createBooking_taxes(t *testing.T) {
+	f := FormInput{BookingRef: "6FBJUN0111", NumberOfPeople: 3, NumberOfChildren: 1, Gross: 1000,
+		IsCleaning: true, IsLaundry: true}
+	tests := []struct {
+		name   string
+		status Status
+		want   []TaxLine
+	}{
+		{"confirmed", Confirmed, []TaxLine{
+			// 2 adults for 7 of the 10 nights
+			{Name: "tourist_tax", Jurisdiction: "Bath", Type: PerPersonPerNight, Rate: 2, Base: 14, Amount: 28},
+			{Name: "occupancy_tax", Jurisdiction: "Bath", Type: PercentOfGross, Rate: 0.05, Base: 1000, Amount: 50},
+			// on the cleaning but not the laundry
+			{Name: "vat", Jurisdiction: "UK", Type: VAT, Rate: 0.2, Base: 35, Amount: 7},
+		}},
+		{"cancelled", Cancelled, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := f
+			f.Status = tt.status
+			b := CreateBooking(f, taxSettings)
+			if !reflect.DeepEqual(b.Taxes, tt.want) {
+				t.Errorf("CreateBooking() taxes = %+v, want %+v", b.Taxes, tt.want)
+			}
+		})
+	}
+
+	row := GetBookingSpreadsheetRow(f, taxSettings)
+	if row.TouristTax != 78 || row.VAT != 7 {
+		t.Errorf("spreadsheet row tourist tax, VAT = %v, %v, want 78, 7", row.TouristTax, row.VAT)
+	}
+	untaxed := Settings{Properties: []Property{taxSettings.Properties[0]}}
+	untaxed.Properties[0].Taxes = nil
+	if want := GetBookingSpreadsheetRow(f, untaxed).OwnerIncome; row.OwnerIncome != want {
+		t.Errorf("spreadsheet row owner income = %v, want %v as without taxes", row.OwnerIncome, want)
+	}
+}
+
+func Test_createBooking_vatServices(t *testing.T) {
+	property := taxSettings.Properties[0]
+	property.Taxes = []TaxRule{{Name: "vat", Jurisdiction: "UK", Type: VAT, Rate: 0.2, Services: []string{"cleaning"}}}
+	property.PerNight = map[string]float64{"cleaning": 5}
+	property.LengthOfStay = []StayAdjustment{{MinNights: 7, Adjustment: -0.1}}
+	settings := Settings{Properties: []Property{property}}
+	f := FormInput{BookingRef: "6FBJUN0111", NumberOfPeople: 2, Gross: 1000, IsCleaning: true, IsLaundry: true}
+	b := CreateBooking(f, settings)
+	// the cleaning, 35, and cleaning by the night, 10 nights at 5, less their
+	// tenth of the length of stay adjustment; not the laundry
+	want := []TaxLine{{Name: "vat", Jurisdiction: "UK", Type: VAT, Rate: 0.2, Base: 76.5, Amount: 15.3}}
+	if len(b.Taxes) != 1 || pence(b.Taxes[0].Base) != want[0].Base || pence(b.Taxes[0].Amount) != want[0].Amount {
+		t.Errorf("CreateBooking() taxes = %+v, want %+v", b.Taxes, want)
+	}
+}
+
+func TestBuildTaxReturn(t *testing.T) {
+	store := NewMemoryStore()
+	for _, f := range []FormInput{
+		{BookingRef: "6FBJUN0111", NumberOfPeople: 2, Gross: 500, IsCleaning: true},
+		{BookingRef: "6FBJUN2022", NumberOfPeople: 1, Gross: 200, IsCleaning: true},
+		// next quarter
+		{BookingRef: "6FBJUL0103", NumberOfPeople: 4, Gross: 300, IsCleaning: true},
+	} {
+		if err := store.Create(CreateBooking(f, taxSettings)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, err := BuildTaxReturn(store, 2017, 2)
+	if err != nil {
+		t.Fatalf("BuildTaxReturn() error = %v", err)
+	}
+	if r.From != Datetime(2017, time.April, 1) || r.To != Datetime(2017, time.July, 1) {
+		t.Errorf("BuildTaxReturn() period = %v to %v", r.From, r.To)
+	}
+	want := []TaxReturnLine{
+		{Jurisdiction: "Bath", Name: "occupancy_tax", Type: PercentOfGross, Bookings: 2, Base: 700, Amount: 35},
+		{Jurisdiction: "Bath", Name: "tourist_tax", Type: PerPersonPerNight, Bookings: 2, Base: 16, Amount: 32},
+		{Jurisdiction: "UK", Name: "vat", Type: VAT, Bookings: 2, Base: 70, Amount: 14},
+	}
+	if !reflect.DeepEqual(r.Lines, want) {
+		t.Errorf("BuildTaxReturn() lines = %+v, want %+v", r.Lines, want)
+	}
+	var out strings.Builder
+	if err := WriteTaxReturn(&out, r); err != nil {
+		t.Fatalf("WriteTaxReturn() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "Q2 2017") {
+		t.Errorf("WriteTaxReturn() = %q", out.String())
+	}
+	if _, err := BuildTaxReturn(store, 2017, 5); err == nil {
+		t.Error("BuildTaxReturn() quarter 5 error = nil")
+	}
+}
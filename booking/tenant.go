@@ -0,0 +1,177 @@
+package booking
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+/*
+ * A group that runs several businesses, e.g. under different brands, keeps
+ * each as a tenant, with its own settings and bookings. As the settings
+ * hold the business's opening date, reference scheme and timezone, and the
+ * bookings are in its own store, no tenant sees another's bookings, guests
+ * or reports. The tenants are listed in a tenants file, like so:
+ *
+ * { "tenants": [
+ *   { "id" : "bath",
+ *     "name" : "Bath Holiday Lets",
+ *     "settings" : "bath/settings.json",
+ *     "store" : "bath/bookings.jsonl" },
+ *   { "id" : "lakes",
+ *     "name" : "Lakeland Cottages",
+ *     "settings" : "lakes/settings.json",
+ *     "store" : "lakes/bookings.jsonl",
+ *     "ledger" : "lakes/ledger.jsonl" }
+ * ]}
+ *
+ * The ledger of what house owners are owed, see ledger.go, defaults to one
+ * beside the store, e.g. bath/bookings.ledger.jsonl. Paths are relative to
+ * the tenants file. Ids are lower case letters, digits
+ * and hyphens, as they are used in URLs.
+ */
+
+// TenantConfig says where a tenant's settings and bookings are kept
+type TenantConfig struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Settings string `json:"settings"`
+	Store    string `json:"store"`
+	Ledger   string `json:"ledger"`
+}
+
+// Tenant is a business with its settings and bookings
+type Tenant struct {
+	ID       string
+	Name     string
+	Settings Settings
+	Store    BookingStore
+}
+
+// ErrTenantNotFound is returned for an id no tenant has
+var ErrTenantNotFound = errors.New("tenant not found")
+
+var tenantID = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// reservedTenantIDs are used in URLs alongside the tenants'
+var reservedTenantIDs = []string{"api"}
+
+// ReadTenants reads a tenants file, checking every tenant has its own id
+// and store
+func ReadTenants(file string) ([]TenantConfig, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var tenants struct {
+		Tenants []TenantConfig `json:"tenants"`
+	}
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if len(tenants.Tenants) == 0 {
+		return nil, fmt.Errorf("%s: no tenants", file)
+	}
+	dir := filepath.Dir(file)
+	ids := make(map[string]bool)
+	stores := make(map[string]string)
+	for i, t := range tenants.Tenants {
+		switch {
+		case !tenantID.MatchString(t.ID) || containsString(reservedTenantIDs, t.ID):
+			return nil, fmt.Errorf("%s: tenant id %q is not lower case letters, digits and hyphens, or is reserved", file, t.ID)
+		case ids[t.ID]:
+			return nil, fmt.Errorf("%s: tenant id %q is there twice", file, t.ID)
+		case t.Settings == "" || t.Store == "":
+			return nil, fmt.Errorf("%s: tenant %s needs settings and a store", file, t.ID)
+		}
+		ids[t.ID] = true
+		t.Settings = relativeTo(dir, t.Settings)
+		t.Store = relativeTo(dir, t.Store)
+		if t.Ledger == "" {
+			t.Ledger = strings.TrimSuffix(t.Store, filepath.Ext(t.Store)) + ".ledger.jsonl"
+		} else {
+			t.Ledger = relativeTo(dir, t.Ledger)
+		}
+		if other, ok := stores[filepath.Clean(t.Store)]; ok {
+			return nil, fmt.Errorf("%s: tenants %s and %s share a store", file, other, t.ID)
+		}
+		stores[filepath.Clean(t.Store)] = t.ID
+		tenants.Tenants[i] = t
+	}
+	return tenants.Tenants, nil
+}
+
+// relativeTo returns path, relative to dir unless it is absolute
+func relativeTo(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// FindTenant returns the tenant with id, or the only tenant if id is ""
+func FindTenant(tenants []TenantConfig, id string) (TenantConfig, error) {
+	if id == "" {
+		if len(tenants) == 1 {
+			return tenants[0], nil
+		}
+		var ids []string
+		for _, t := range tenants {
+			ids = append(ids, t.ID)
+		}
+		return TenantConfig{}, fmt.Errorf("choose a tenant, one of %s", strings.Join(ids, ", "))
+	}
+	for _, t := range tenants {
+		if t.ID == id {
+			return t, nil
+		}
+	}
+	return TenantConfig{}, ErrTenantNotFound
+}
+
+// LoadSettings reads and validates the tenant's settings
+func (c TenantConfig) LoadSettings() (Settings, error) {
+	data, err := ioutil.ReadFile(c.Settings)
+	if err != nil {
+		return Settings{}, err
+	}
+	settings, err := LoadSettings(data)
+	if err != nil {
+		return settings, fmt.Errorf("tenant %s: %v", c.ID, err)
+	}
+	return settings, nil
+}
+
+// Open loads the tenant's settings and opens its store
+func (c TenantConfig) Open() (Tenant, error) {
+	settings, err := c.LoadSettings()
+	if err != nil {
+		return Tenant{}, err
+	}
+	store, err := OpenFileStore(c.Store)
+	if err != nil {
+		return Tenant{}, err
+	}
+	return Tenant{ID: c.ID, Name: c.Name, Settings: settings, Store: store}, nil
+}
+
+// OpenTenants opens every tenant in a tenants file
+func OpenTenants(file string) ([]Tenant, error) {
+	configs, err := ReadTenants(file)
+	if err != nil {
+		return nil, err
+	}
+	var tenants []Tenant
+	for _, c := range configs {
+		t, err := c.Open()
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, t)
+	}
+	return tenants, nil
+}
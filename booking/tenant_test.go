@@ -0,0 +1,107 @@
+package booking
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// writeTenants writes a tenants file, and settings for each tenant, to a
+// temporary directory
+func writeTenants(t *testing.T, tenants string) string {
+	dir := t.TempDir()
+	for _, name := range []string{"bath.json", "lakes.json"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(petFriendlySettings), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file := filepath.Join(dir, "tenants.json")
+	if err := ioutil.WriteFile(file, []byte(tenants), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestReadTenants(t *testing.T) {
+	tests := []struct {
+		name    string
+		tenants string
+		wantErr bool
+	}{
+		{"two", `{"tenants": [{"id": "bath", "settings": "bath.json", "store": "bath.jsonl"},
+			{"id": "lakes", "settings": "lakes.json", "store": "lakes.jsonl"}]}`, false},
+		{"none", `{"tenants": []}`, true},
+		{"not json", `tenants`, true},
+		{"bad id", `{"tenants": [{"id": "Bath Lets", "settings": "bath.json", "store": "bath.jsonl"}]}`, true},
+		{"reserved id", `{"tenants": [{"id": "api", "settings": "bath.json", "store": "bath.jsonl"}]}`, true},
+		{"no store", `{"tenants": [{"id": "bath", "settings": "bath.json"}]}`, true},
+		{"same id", `{"tenants": [{"id": "bath", "settings": "bath.json", "store": "bath.jsonl"},
+			{"id": "bath", "settings": "lakes.json", "store": "lakes.jsonl"}]}`, true},
+		{"shared store", `{"tenants": [{"id": "bath", "settings": "bath.json", "store": "bookings.jsonl"},
+			{"id": "lakes", "settings": "lakes.json", "store": "./bookings.jsonl"}]}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeTenants(t, tt.tenants)
+			tenants, err := ReadTenants(file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadTenants() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && tenants[1].Store != filepath.Join(filepath.Dir(file), "lakes.jsonl") {
+				t.Errorf("ReadTenants() store = %v, want it relative to the tenants file", tenants[1].Store)
+			}
+			if err == nil && tenants[1].Ledger != filepath.Join(filepath.Dir(file), "lakes.ledger.jsonl") {
+				t.Errorf("ReadTenants() ledger = %v, want it beside the store", tenants[1].Ledger)
+			}
+		})
+	}
+}
+
+func TestFindTenant(t *testing.T) {
+	tenants := []TenantConfig{{ID: "bath"}, {ID: "lakes"}}
+	if c, err := FindTenant(tenants, "lakes"); err != nil || c.ID != "lakes" {
+		t.Errorf("FindTenant(lakes) = %v, %v", c, err)
+	}
+	if _, err := FindTenant(tenants, "york"); err != ErrTenantNotFound {
+		t.Errorf("FindTenant(york) error = %v, want %v", err, ErrTenantNotFound)
+	}
+	if _, err := FindTenant(tenants, ""); err == nil {
+		t.Errorf("FindTenant() of two tenants did not fail")
+	}
+	if c, err := FindTenant(tenants[:1], ""); err != nil || c.ID != "bath" {
+		t.Errorf("FindTenant() of one tenant = %v, %v", c, err)
+	}
+}
+
+func TestTenantsAreIsolated(t *testing.T) {
+	file := writeTenants(t, `{"tenants": [{"id": "bath", "settings": "bath.json", "store": "bath.jsonl"},
+		{"id": "lakes", "settings": "lakes.json", "store": "lakes.jsonl"}]}`)
+	tenants, err := OpenTenants(file)
+	if err != nil {
+		t.Fatalf("OpenTenants() error = %v", err)
+	}
+	bath, lakes := tenants[0], tenants[1]
+	b := CreateBooking(FormInput{BookingRef: "6DHJUN1719", NumberOfPeople: 2, Gross: 400}, bath.Settings)
+	if err := bath.Store.Create(b); err != nil {
+		t.Fatal(err)
+	}
+	// the tenants' stores are reopened from their files
+	if tenants, err = OpenTenants(file); err != nil {
+		t.Fatalf("OpenTenants() error = %v", err)
+	}
+	if _, err := tenants[0].Store.Get("6DHJUN1719"); err != nil {
+		t.Errorf("bath's booking is not in its store: %v", err)
+	}
+	if _, err := tenants[1].Store.Get("6DHJUN1719"); err != ErrBookingNotFound {
+		t.Errorf("bath's booking is in lakes' store, error = %v", err)
+	}
+	reports, err := BuildOwnerReports(lakes.Store, lakes.Settings, Datetime(2017, 1, 1), Datetime(2018, 1, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range reports {
+		if len(r.Bookings) > 0 {
+			t.Errorf("lakes' report for %s has bath's bookings: %v", r.Property.ShortName, r.Bookings)
+		}
+	}
+}
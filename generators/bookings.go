@@ -0,0 +1,124 @@
+package generators
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"time"
+
+	"github.com/tintinnabulate/supreme-garbanzo/booking"
+)
+
+var firstNames = []string{"Ann", "Bob", "Cerys", "Dev", "Eve", "Femi"}
+var lastNames = []string{"Smith", "Jones", "O'Brien", "McDonald", "Nguyen", "Okafor"}
+
+// money returns a random amount from 0 up to max, in whole pence
+func money(r *rand.Rand, max int) float64 {
+	return float64(r.Intn(max*100)) / 100
+}
+
+// prices returns random prices for parties of one to six, which never fall
+// as the party grows
+func prices(r *rand.Rand) []float64 {
+	ps := make([]float64, 6)
+	price := money(r, 20)
+	for i := range ps {
+		price += money(r, 10)
+		ps[i] = price
+	}
+	return ps
+}
+
+// randomShortName returns two random capital letters
+func randomShortName(r *rand.Rand) string {
+	return string([]byte{byte('A' + r.Intn(26)), byte('A' + r.Intn(26))})
+}
+
+// Generate allows Property to be used within quickcheck scenarios.
+func (Property) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(Property(randomProperty(r, randomShortName(r))))
+}
+
+func randomProperty(r *rand.Rand, shortName string) booking.Property {
+	return booking.Property{
+		LongName:             "Property " + shortName,
+		ShortName:            shortName,
+		Calendar:             fmt.Sprintf("%s@mycalendar.com", shortName),
+		Commission:           money(r, 1) * 0.3,
+		BookingCommission:    money(r, 1) * 0.2,
+		HouseOwnerCommission: money(r, 1) * 0.3,
+		Greeting:             money(r, 30),
+		Laundry:              prices(r),
+		Cleaning:             money(r, 50),
+		Consumables:          prices(r),
+	}
+}
+
+// Generate allows Settings to be used within quickcheck scenarios. The
+// properties have distinct short names.
+func (Settings) Generate(r *rand.Rand, size int) reflect.Value {
+	var s booking.Settings
+	seen := make(map[string]bool)
+	for n := 1 + r.Intn(4); len(s.Properties) < n; {
+		shortName := randomShortName(r)
+		if !seen[shortName] {
+			seen[shortName] = true
+			s.Properties = append(s.Properties, randomProperty(r, shortName))
+		}
+	}
+	return reflect.ValueOf(Settings(s))
+}
+
+// BookingRef is a valid booking reference: the years since the business
+// opened, a property short name, and the month and day of arrival and day of
+// departure. The stay is from 1 to 27 nights, so the month of departure is
+// never ambiguous.
+type BookingRef string
+
+// Generate allows BookingRef to be used within quickcheck scenarios.
+func (BookingRef) Generate(r *rand.Rand, size int) reflect.Value {
+	ref, _, _ := randomBookingRef(r, booking.Property{ShortName: randomShortName(r)}, booking.Settings{})
+	return reflect.ValueOf(ref)
+}
+
+// randomBookingRef returns a valid reference for a stay at the property, by
+// the reference scheme of s, and the dates of the stay
+func randomBookingRef(r *rand.Rand, p booking.Property, s booking.Settings) (BookingRef, time.Time, time.Time) {
+	opening := s.OpeningDate().Year()
+	arrival := time.Date(opening+r.Intn(20), time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, r.Intn(365))
+	departure := arrival.AddDate(0, 0, 1+r.Intn(27))
+	ref := booking.CreateBookingRef(booking.Booking{Property: p, Arrival: arrival, Departure: departure,
+		BookingDate: arrival}, s)
+	return BookingRef(ref), arrival, departure
+}
+
+// Generate allows FormInput to be used within quickcheck scenarios. The
+// booking is for a property with a random short name; use RandomFormInput
+// for bookings for the properties of some Settings.
+func (FormInput) Generate(r *rand.Rand, size int) reflect.Value {
+	s := Settings{Properties: []booking.Property{randomProperty(r, randomShortName(r))}}
+	return reflect.ValueOf(RandomFormInput(r, s))
+}
+
+// RandomFormInput returns a random, valid booking for one of the properties
+// in s, made in the year of arrival, on or before the day of arrival
+func RandomFormInput(r *rand.Rand, s Settings) FormInput {
+	p := s.Properties[r.Intn(len(s.Properties))]
+	ref, arrival, _ := randomBookingRef(r, p, booking.Settings(s))
+	first, last := firstNames[r.Intn(len(firstNames))], lastNames[r.Intn(len(lastNames))]
+	return FormInput{
+		BookingRef:     string(ref),
+		FirstName:      first,
+		LastName:       last,
+		Email:          fmt.Sprintf("%s.%s@example.com", first, last),
+		Mobile:         fmt.Sprintf("07700 9%.5d", r.Intn(100000)),
+		Source:         booking.Source(r.Intn(int(booking.Other)) + 1),
+		NumberOfPeople: 1 + r.Intn(8),
+		Gross:          money(r, 5000),
+		IsGreeting:     r.Intn(2) == 0,
+		IsLaundry:      r.Intn(2) == 0,
+		IsCleaning:     r.Intn(2) == 0,
+		IsConsumables:  r.Intn(2) == 0,
+		BookingDate:    arrival.AddDate(0, 0, -r.Intn(arrival.YearDay())),
+	}
+}
@@ -1,11 +1,12 @@
 package generators
 
 import (
-	"fmt"
 	"math/rand"
 	"reflect"
 	"testing/quick"
 	"time"
+
+	"github.com/tintinnabulate/supreme-garbanzo/booking"
 )
 
 // Date creates a random date useful for randomness
@@ -32,115 +33,30 @@ func (d Date) String() string {
 	return d.Date().Format(time.RFC3339)
 }
 
-// Property is used to unmarshal a JSON file of the above form
-type Property struct {
-	LongName             string
-	ShortName            string
-	Calendar             string
-	Commission           float64
-	BookingCommission    float64
-	HouseOwnerCommission float64
-	Greeting             float64
-	Laundry              []float64
-	Cleaning             float64
-	Consumables          []float64
-}
+// Property is a booking.Property that can be generated
+type Property booking.Property
 
-// Settings holds the settings for each property
-type Settings struct {
-	Properties []Property `json:"properties"`
-}
+// Settings is a booking.Settings that can be generated
+type Settings booking.Settings
 
-// Source is an Enum
-type Source int
-
-// BookingCom and others are sources the booking originated from
-const (
-	BookingCom Source = 1 + iota
-	AirBnb
-	Email
-	Phone
-	VisitBath
-	Other
-	nSources = int(Other)
-)
+// Source is a booking.Source that can be generated
+type Source booking.Source
 
 // Generate : generate a random Source
 func (Source) Generate(rand *rand.Rand, size int) reflect.Value {
-	return reflect.ValueOf(Source(rand.Intn(nSources)))
+	return reflect.ValueOf(Source(rand.Intn(int(booking.Other)) + 1))
 }
 
-// Month : month
-type Month int
-
-// Jan : jan
-const (
-	Jan Month = 1 + iota
-	Feb
-	Mar
-	Apr
-	May
-	Jun
-	Jul
-	Aug
-	Sep
-	Oct
-	Nov
-	Dec
-	nMonths = int(Dec)
-)
-
-var months = map[string]time.Month{
-	"JAN": time.January,
-	"FEB": time.February,
-	"MAR": time.March,
-	"APR": time.April,
-	"MAY": time.May,
-	"JUN": time.June,
-	"JUL": time.July,
-	"AUG": time.August,
-	"SEP": time.September,
-	"OCT": time.October,
-	"NOV": time.November,
-	"DEC": time.December,
-}
+// Month is a booking.Month that can be generated
+type Month booking.Month
 
 // Generate : generate a random month
 func (m Month) Generate(rand *rand.Rand, size int) reflect.Value {
-	return reflect.ValueOf(Month(rand.Intn(nMonths)))
-}
-
-// FormInput holds all the form Inputs from the user
-type FormInput struct {
-	BookingRef     string
-	FirstName      string
-	LastName       string
-	Email          string
-	Mobile         string
-	Notes          string
-	Source         Source
-	NumberOfPeople int
-	Gross          float64
-	IsGreeting     bool
-	IsLaundry      bool
-	IsCleaning     bool
-	IsConsumables  bool
-	BookingDate    time.Time
+	return reflect.ValueOf(Month(rand.Intn(int(booking.Dec)) + 1))
 }
 
-// Booking holds a booking
-type Booking struct {
-	Form          FormInput
-	Property      Property
-	Arrival       time.Time
-	Departure     time.Time
-	BookingDate   time.Time
-	BookingFee    float64
-	HouseOwnerFee float64
-	Net           float64
-	TotalFees     float64
-	OwnerIncome   float64
-}
+// FormInput is a booking.FormInput that can be generated
+type FormInput booking.FormInput
 
 // GenerateRandomTime : generates a random datetime
 func GenerateRandomTime(rnd *rand.Rand) (reflect.Value, bool) {
@@ -155,12 +71,3 @@ func GenerateRandomMonth(rnd *rand.Rand) (reflect.Value, bool) {
 	v, ok := quick.Value(t, rnd)
 	return v, ok
 }
-
-// Run : run
-func Run() {
-	rnd := rand.New(rand.NewSource(42))
-	v, _ := GenerateRandomTime(rnd)
-	fmt.Println("here's a time:", v.Interface())
-	v, _ = GenerateRandomMonth(rnd)
-	fmt.Println("here's a month:", v.Interface())
-}
@@ -0,0 +1,24 @@
+package generators
+
+import (
+	"testing"
+	"testing/quick"
+
+	"github.com/tintinnabulate/supreme-garbanzo/booking"
+)
+
+func TestSource_Generate(t *testing.T) {
+	inRange := func(s Source) bool {
+		return booking.Source(s) >= booking.BookingCom && booking.Source(s) <= booking.Other
+	}
+	if err := quick.Check(inRange, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMonth_Generate(t *testing.T) {
+	inRange := func(m Month) bool { return booking.Month(m) >= booking.Jan && booking.Month(m) <= booking.Dec }
+	if err := quick.Check(inRange, nil); err != nil {
+		t.Error(err)
+	}
+}
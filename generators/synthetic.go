@@ -0,0 +1,264 @@
+package generators
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tintinnabulate/supreme-garbanzo/booking"
+)
+
+// DefaultDemand is how likely a property is to be booked in each month, from
+// January to December, relative to the busiest month
+var DefaultDemand = [12]float64{0.3, 0.35, 0.45, 0.6, 0.7, 0.85, 0.95, 1, 0.75, 0.55, 0.35, 0.5}
+
+// DefaultSourceMix weights where bookings come from
+var DefaultSourceMix = map[booking.Source]float64{
+	booking.BookingCom: 35,
+	booking.AirBnb:     30,
+	booking.Email:      15,
+	booking.Phone:      10,
+	booking.VisitBath:  5,
+	booking.Other:      5,
+}
+
+// Scenario describes a synthetic bookings spreadsheet
+type Scenario struct {
+	// Seed makes the same scenario generate the same bookings
+	Seed     int64
+	Settings booking.Settings
+	// Properties are the short names of the properties booked, or all of them if empty
+	Properties []string
+	// Bookings arrive on or after From and depart on or before To
+	From time.Time
+	To   time.Time
+	// Demand is as DefaultDemand, which it defaults to
+	Demand *[12]float64
+	// SourceMix is as DefaultSourceMix, which it defaults to
+	SourceMix map[booking.Source]float64
+	// Occupancy scales how often stays start, from 0 to 1, 0.8 by default
+	Occupancy float64
+}
+
+// Validate checks the scenario's properties are all in its settings, and its
+// demand and source mix can be drawn from
+func (s Scenario) Validate() error {
+	var names []string
+	for _, p := range s.Settings.Properties {
+		names = append(names, p.ShortName)
+	}
+	for _, name := range s.Properties {
+		if !contains(names, name) {
+			return fmt.Errorf("no property %q in the settings", name)
+		}
+	}
+	if s.Demand != nil {
+		if err := validateWeights(s.Demand[:], "demand"); err != nil {
+			return err
+		}
+	}
+	if s.SourceMix != nil {
+		var weights []float64
+		for _, weight := range s.SourceMix {
+			weights = append(weights, weight)
+		}
+		if err := validateWeights(weights, "source mix"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateWeights checks weights are not negative, and not all 0
+func validateWeights(weights []float64, what string) error {
+	total := 0.0
+	for _, weight := range weights {
+		if weight < 0 {
+			return fmt.Errorf("%s %g is negative", what, weight)
+		}
+		total += weight
+	}
+	if total == 0 {
+		return fmt.Errorf("%s is all 0", what)
+	}
+	return nil
+}
+
+// ParseDemand reads a demand as 12 comma separated weights, from January to
+// December, e.g. "0.3,0.35,0.45,0.6,0.7,0.85,0.95,1,0.75,0.55,0.35,0.5"
+func ParseDemand(value string) (*[12]float64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 12 {
+		return nil, fmt.Errorf("demand %q has %d months, want 12", value, len(parts))
+	}
+	var demand [12]float64
+	for i, part := range parts {
+		weight, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("demand for %s %q is not a number", time.Month(i+1), part)
+		}
+		demand[i] = weight
+	}
+	if err := validateWeights(demand[:], "demand"); err != nil {
+		return nil, err
+	}
+	return &demand, nil
+}
+
+// ParseSourceMix reads a source mix as comma separated source=weight pairs,
+// with sources named as in the bookings spreadsheet, e.g.
+// "booking.com=35,airbnb=30,email=15". Sources left out are never picked.
+func ParseSourceMix(value string) (map[booking.Source]float64, error) {
+	mix := make(map[booking.Source]float64)
+	var weights []float64
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("source mix %q is not like booking.com=35", pair)
+		}
+		source, ok := booking.ParseSource(strings.TrimSpace(parts[0]))
+		if !ok {
+			return nil, fmt.Errorf("unknown source %q", parts[0])
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("source mix for %s %q is not a number", source, parts[1])
+		}
+		mix[source] = weight
+		weights = append(weights, weight)
+	}
+	if err := validateWeights(weights, "source mix"); err != nil {
+		return nil, err
+	}
+	return mix, nil
+}
+
+// Bookings generates the scenario's bookings, property by property in order
+// of arrival. A property's stays never overlap, though one guest may arrive
+// on the day another departs.
+func (s Scenario) Bookings() []booking.FormInput {
+	r := rand.New(rand.NewSource(s.Seed))
+	var forms []booking.FormInput
+	for _, p := range s.Settings.Properties {
+		if len(s.Properties) > 0 && !contains(s.Properties, p.ShortName) {
+			continue
+		}
+		forms = append(forms, s.propertyBookings(r, p)...)
+	}
+	return forms
+}
+
+func (s Scenario) propertyBookings(r *rand.Rand, p booking.Property) []booking.FormInput {
+	demand := s.demand()
+	occupancy := s.Occupancy
+	if occupancy == 0 {
+		occupancy = 0.8
+	}
+	var forms []booking.FormInput
+	for day := s.From; day.Before(s.To); {
+		nights := 1 + r.Intn(3) + r.Intn(5)*r.Intn(3)
+		departure := day.AddDate(0, 0, nights)
+		// a stay starts on a free day with a chance that rises with demand;
+		// as stays average four nights, dividing by four keeps the property
+		// from being booked solid
+		start := occupancy * demand[day.Month()-1] / 4
+		if departure.After(s.To) || r.Float64() >= start {
+			day = day.AddDate(0, 0, 1)
+			continue
+		}
+		f, err := s.form(r, p, day, departure, nights)
+		if err != nil {
+			day = day.AddDate(0, 0, 1)
+			continue
+		}
+		forms = append(forms, f)
+		day = departure
+	}
+	return forms
+}
+
+// form makes up a booking for a stay, booked up to 90 days ahead but in the
+// year of arrival, as the reference holds the year it was booked
+func (s Scenario) form(r *rand.Rand, p booking.Property, arrival, departure time.Time, nights int) (booking.FormInput, error) {
+	booked := arrival.AddDate(0, 0, -r.Intn(90))
+	if booked.Year() != arrival.Year() {
+		booked = booking.Datetime(arrival.Year(), time.January, 1)
+	}
+	ref, err := booking.BookingRefFor(p, arrival, departure, booked, s.Settings)
+	if err != nil {
+		return booking.FormInput{}, err
+	}
+	maxPeople := p.MaxOccupancy
+	if maxPeople == 0 {
+		maxPeople = 6
+	}
+	first, last := firstNames[r.Intn(len(firstNames))], lastNames[r.Intn(len(lastNames))]
+	nightly := 60 + 40*s.demand()[arrival.Month()-1] + money(r, 40)
+	return booking.FormInput{
+		BookingRef:     ref,
+		FirstName:      first,
+		LastName:       last,
+		Email:          fmt.Sprintf("%s.%s%d@example.com", first, last, r.Intn(1000)),
+		Mobile:         fmt.Sprintf("07700 9%.5d", r.Intn(100000)),
+		Source:         s.source(r),
+		NumberOfPeople: 1 + r.Intn(maxPeople),
+		Gross:          float64(int(nightly*float64(nights)*100)) / 100,
+		IsGreeting:     true,
+		IsLaundry:      true,
+		IsCleaning:     true,
+		IsConsumables:  true,
+		BookingDate:    booked,
+	}, nil
+}
+
+func (s Scenario) demand() [12]float64 {
+	if s.Demand == nil {
+		return DefaultDemand
+	}
+	return *s.Demand
+}
+
+// source picks where a booking came from, by the scenario's source mix
+func (s Scenario) source(r *rand.Rand) booking.Source {
+	mix := s.SourceMix
+	if mix == nil {
+		mix = DefaultSourceMix
+	}
+	total := 0.0
+	for x := booking.BookingCom; x <= booking.Other; x++ {
+		total += mix[x]
+	}
+	pick := r.Float64() * total
+	for x := booking.BookingCom; x <= booking.Other; x++ {
+		if pick < mix[x] {
+			return x
+		}
+		pick -= mix[x]
+	}
+	return booking.Other
+}
+
+// WriteCSV writes the scenario's bookings as a bookings spreadsheet, which
+// booking.ParseCSV reads back, unless the scenario is not valid
+func (s Scenario) WriteCSV(out io.Writer) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	var spreadsheet booking.Spreadsheet
+	for _, f := range s.Bookings() {
+		spreadsheet.Rows = append(spreadsheet.Rows, booking.GetBookingSpreadsheetRow(f, s.Settings))
+	}
+	return booking.WriteSpreadsheet(out, spreadsheet)
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
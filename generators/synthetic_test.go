@@ -0,0 +1,153 @@
+package generators
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tintinnabulate/supreme-garbanzo/booking"
+)
+
+var scenarioSettings = booking.Settings{Properties: []booking.Property{
+	{LongName: "FooBarBaz", ShortName: "FB", Commission: 0.1, HouseOwnerCommission: 0.1,
+		Laundry: []float64{10}, Consumables: []float64{15}, Cleaning: 35},
+	{LongName: "WibbleWobbleWoo", ShortName: "WW", Commission: 0.2, MaxOccupancy: 4,
+		Laundry: []float64{15}, Consumables: []float64{15}, Cleaning: 35},
+}}
+
+func testScenario() Scenario {
+	return Scenario{Seed: 7, Settings: scenarioSettings,
+		From: booking.Datetime(2017, time.January, 1), To: booking.Datetime(2018, time.January, 1)}
+}
+
+func TestScenario_Bookings(t *testing.T) {
+	s := testScenario()
+	forms := s.Bookings()
+	if len(forms) < 50 {
+		t.Fatalf("Bookings() = %d bookings, want a busy year", len(forms))
+	}
+	if !reflect.DeepEqual(forms, s.Bookings()) {
+		t.Error("Bookings() differs for the same seed")
+	}
+	departures := make(map[string]time.Time)
+	for _, f := range forms {
+		b := booking.CreateBooking(f, s.Settings)
+		if b.Arrival.Before(s.From) || b.Departure.After(s.To) {
+			t.Errorf("%s is outside the scenario's dates", f.BookingRef)
+		}
+		if last, ok := departures[b.Property.ShortName]; ok && b.Arrival.Before(last) {
+			t.Errorf("%s arrives before the last guest at %s departs", f.BookingRef, b.Property.ShortName)
+		}
+		departures[b.Property.ShortName] = b.Departure
+		if err := b.Property.CheckPartySize(f.NumberOfPeople); err != nil {
+			t.Errorf("%s: %v", f.BookingRef, err)
+		}
+	}
+	summer, winter := 0, 0
+	for _, f := range forms {
+		switch booking.CreateBooking(f, s.Settings).Arrival.Month() {
+		case time.July, time.August:
+			summer++
+		case time.January, time.February:
+			winter++
+		}
+	}
+	if summer <= winter {
+		t.Errorf("Bookings() has %d summer arrivals and %d winter ones, want more in summer", summer, winter)
+	}
+}
+
+func TestScenario_options(t *testing.T) {
+	s := testScenario()
+	s.Properties = []string{"WW"}
+	s.SourceMix = map[booking.Source]float64{booking.AirBnb: 1}
+	for _, f := range s.Bookings() {
+		if f.BookingRef[1:3] != "WW" || f.Source != booking.AirBnb {
+			t.Errorf("Bookings() = %s from %s, want WW bookings from airbnb", f.BookingRef, f.Source)
+		}
+	}
+}
+
+func TestScenario_WriteCSV(t *testing.T) {
+	s := testScenario()
+	file := filepath.Join(t.TempDir(), "bookings.csv")
+	out, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.WriteCSV(out); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	out.Close()
+	forms, err := booking.ParseCSV(file)
+	if err != nil {
+		t.Fatalf("ParseCSV() error = %v", err)
+	}
+	want := s.Bookings()
+	if len(forms) != len(want) {
+		t.Fatalf("ParseCSV() = %d bookings, want %d", len(forms), len(want))
+	}
+	for i := range forms {
+		if forms[i].BookingRef != want[i].BookingRef || forms[i].Gross != want[i].Gross {
+			t.Errorf("ParseCSV() row %d = %s %.2f, want %s %.2f", i, forms[i].BookingRef, forms[i].Gross,
+				want[i].BookingRef, want[i].Gross)
+		}
+	}
+}
+
+func TestScenario_Validate(t *testing.T) {
+	unknown := testScenario()
+	unknown.Properties = []string{"FB", "XX"}
+	noDemand := testScenario()
+	noDemand.Demand = &[12]float64{}
+	negativeMix := testScenario()
+	negativeMix.SourceMix = map[booking.Source]float64{booking.AirBnb: 1, booking.Email: -1}
+	tests := []struct {
+		name     string
+		scenario Scenario
+		wantErr  bool
+	}{
+		{"valid", testScenario(), false},
+		{"unknown property", unknown, true},
+		{"no demand", noDemand, true},
+		{"negative source mix", negativeMix, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.scenario.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := tt.scenario.WriteCSV(ioutil.Discard); (err != nil) != tt.wantErr {
+				t.Errorf("WriteCSV() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseDemand(t *testing.T) {
+	demand, err := ParseDemand("0.3,0.35,0.45,0.6,0.7,0.85,0.95,1,0.75,0.55,0.35,0.5")
+	if err != nil || *demand != DefaultDemand {
+		t.Errorf("ParseDemand() = %v, %v, want %v", demand, err, DefaultDemand)
+	}
+	for _, value := range []string{"", "1,1,1", "1,1,1,1,1,1,1,1,1,1,1,x", "0,0,0,0,0,0,0,0,0,0,0,0", "1,1,1,1,1,1,1,1,1,1,1,-1"} {
+		if _, err := ParseDemand(value); err == nil {
+			t.Errorf("ParseDemand(%q) error = nil, want an error", value)
+		}
+	}
+}
+
+func TestParseSourceMix(t *testing.T) {
+	mix, err := ParseSourceMix("booking.com=35, airbnb=65")
+	want := map[booking.Source]float64{booking.BookingCom: 35, booking.AirBnb: 65}
+	if err != nil || !reflect.DeepEqual(mix, want) {
+		t.Errorf("ParseSourceMix() = %v, %v, want %v", mix, err, want)
+	}
+	for _, value := range []string{"", "airbnb", "expedia=10", "airbnb=lots", "airbnb=0", "airbnb=-1"} {
+		if _, err := ParseSourceMix(value); err == nil {
+			t.Errorf("ParseSourceMix(%q) error = nil, want an error", value)
+		}
+	}
+}
@@ -1,22 +1,659 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
+	"fmt"
 	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/tintinnabulate/supreme-garbanzo/booking"
 	"github.com/tintinnabulate/supreme-garbanzo/generators"
 )
 
+// clock tells the commands the time now. They take the date from it in the
+// business's timezone, with today, rather than from the machine's.
+var clock booking.Clock = time.Now
+
+// today returns the date now in the business's timezone
+func today(settings booking.Settings) time.Time {
+	return settings.Today(clock())
+}
+
 func check(e error) {
 	if e != nil {
 		panic(e)
 	}
 }
 
-func main() {
-	generators.Run()
-	jsonByteArray, err := ioutil.ReadFile("settings.json")
+// tenantFlags choose the tenant a command works on, from a tenants file, see
+// booking/tenant.go. Without a tenants file, commands work on the one
+// business whose -settings and -store they are given. With one, the files
+// commands read and write by default, e.g. out.csv, are in a directory named
+// after the tenant, e.g. bath/out.csv, so tenants do not overwrite each
+// other's.
+type tenantFlags struct {
+	file *string
+	id   *string
+	// chosen is the tenant, once read from the tenants file
+	chosen *booking.TenantConfig
+}
+
+func addTenantFlags(fs *flag.FlagSet) *tenantFlags {
+	return &tenantFlags{
+		file: fs.String("tenants", "", "tenants file, to work on one of several businesses"),
+		id:   fs.String("tenant", "", "id of the tenant in -tenants to work on (default the only one)"),
+	}
+}
+
+// config returns the chosen tenant, or false without a tenants file
+func (t *tenantFlags) config() (booking.TenantConfig, bool) {
+	if t.chosen != nil {
+		return *t.chosen, true
+	}
+	if *t.file == "" {
+		if *t.id != "" {
+			log.Fatal("-tenant needs a -tenants file")
+		}
+		return booking.TenantConfig{}, false
+	}
+	tenants, err := booking.ReadTenants(*t.file)
+	check(err)
+	c, err := booking.FindTenant(tenants, *t.id)
+	if err == booking.ErrTenantNotFound {
+		log.Fatalf("no tenant %q in %s", *t.id, *t.file)
+	}
+	check(err)
+	t.chosen = &c
+	return c, true
+}
+
+// settings loads the tenant's settings, or those in settingsFile
+func (t *tenantFlags) settings(settingsFile string) booking.Settings {
+	if c, ok := t.config(); ok {
+		settings, err := c.LoadSettings()
+		check(err)
+		return settings
+	}
+	return loadSettings(settingsFile)
+}
+
+// store opens the tenant's store, or the one in storeFile
+func (t *tenantFlags) store(storeFile string) *booking.FileStore {
+	if c, ok := t.config(); ok {
+		storeFile = c.Store
+	}
+	store, err := booking.OpenFileStore(storeFile)
+	check(err)
+	return store
+}
+
+// ledger opens the tenant's ledger of what house owners are owed, or the
+// one in ledgerFile
+func (t *tenantFlags) ledger(ledgerFile string) *booking.FileLedger {
+	if c, ok := t.config(); ok {
+		ledgerFile = c.Ledger
+	}
+	ledger, err := booking.OpenFileLedger(ledgerFile)
+	check(err)
+	return ledger
+}
+
+// dir returns a directory of dir for the tenant to write files to, so
+// tenants do not mix their files
+func (t *tenantFlags) dir(dir string) string {
+	if c, ok := t.config(); ok {
+		return filepath.Join(dir, c.ID)
+	}
+	return dir
+}
+
+// path returns file in the tenant's directory, see dir, for the files
+// commands read and write by default
+func (t *tenantFlags) path(file string) string {
+	return filepath.Join(t.dir(filepath.Dir(file)), filepath.Base(file))
+}
+
+// pathFlag returns the file a flag names: in the tenant's directory if it
+// was left to default, and as given otherwise
+func (t *tenantFlags) pathFlag(fs *flag.FlagSet, name string) string {
+	file := fs.Lookup(name).Value.String()
+	given := false
+	fs.Visit(func(f *flag.Flag) { given = given || f.Name == name })
+	if given {
+		return file
+	}
+	return t.path(file)
+}
+
+// pathArg returns the file given as the first argument, or file in the
+// tenant's directory
+func (t *tenantFlags) pathArg(fs *flag.FlagSet, file string) string {
+	if fs.NArg() > 0 {
+		return fs.Arg(0)
+	}
+	return t.path(file)
+}
+
+// createFile creates file, and the directory it is in
+func createFile(file string) *os.File {
+	check(os.MkdirAll(filepath.Dir(file), 0755))
+	w, err := os.Create(file)
+	check(err)
+	return w
+}
+
+func loadSettings(file string) booking.Settings {
+	jsonByteArray, err := ioutil.ReadFile(file)
+	check(err)
+	settings, err := booking.LoadSettings(jsonByteArray)
+	check(err)
+	return settings
+}
+
+func parseDateFlag(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	date, err := booking.ParseDate(value)
+	check(err)
+	return date
+}
+
+// runFix recalculates every booking in the bookings CSV and writes out.csv,
+// in the tenant's directory with -tenants
+func runFix(args []string) {
+	fs := flag.NewFlagSet("fix", flag.ExitOnError)
+	tenant := addTenantFlags(fs)
+	settingsFile := fs.String("settings", "settings.json", "settings file")
+	fs.Parse(args)
+	settings := tenant.settings(*settingsFile)
+	spreadsheet := booking.FixCSV(tenant.pathArg(fs, "bookings.csv"), settings)
+	w := createFile(tenant.path("out.csv"))
+	check(booking.WriteSpreadsheet(w, spreadsheet))
+	check(w.Close())
+}
+
+// runServe serves the booking entry form and API over HTTP: of every tenant
+// in -tenants, each under /<tenant id>/, unless -tenant chooses one
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	tenant := addTenantFlags(fs)
+	settingsFile := fs.String("settings", "settings.json", "settings file")
+	storeFile := fs.String("store", "bookings.jsonl", "booking store file")
+	addr := fs.String("addr", ":8080", "address to listen on")
+	fs.Parse(args)
+	var handler http.Handler
+	if *tenant.file != "" && *tenant.id == "" {
+		tenants, err := booking.OpenTenants(*tenant.file)
+		check(err)
+		handler = newTenantsServer(tenants)
+	} else {
+		handler = newServer(tenant.settings(*settingsFile), tenant.store(*storeFile))
+	}
+	log.Println("listening on", *addr)
+	log.Fatal(http.ListenAndServe(*addr, handler))
+}
+
+// runImport calculates the bookings in a CSV and saves them in the store
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	tenant := addTenantFlags(fs)
+	settingsFile := fs.String("settings", "settings.json", "settings file")
+	storeFile := fs.String("store", "bookings.jsonl", "booking store file")
+	fs.Parse(args)
+	settings := tenant.settings(*settingsFile)
+	store := tenant.store(*storeFile)
+	file := tenant.pathArg(fs, "bookings.csv")
+	report, err := booking.ImportCSV(file, settings, store)
+	for _, p := range report.Rejected {
+		log.Println("rejected", p)
+	}
+	for _, p := range report.Warnings {
+		log.Println("warning", p)
+	}
+	check(err)
+	log.Printf("imported %d bookings from %s, rejected %d, with %d warnings",
+		report.Imported, file, len(report.Rejected), len(report.Warnings))
+}
+
+// runExport writes the bookings in the store to a CSV
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	tenant := addTenantFlags(fs)
+	storeFile := fs.String("store", "bookings.jsonl", "booking store file")
+	property := fs.String("property", "", "only export bookings for this property short name")
+	from := fs.String("from", "", "only export arrivals on or after this date")
+	to := fs.String("to", "", "only export arrivals before this date")
+	fs.Parse(args)
+	store := tenant.store(*storeFile)
+	file := tenant.pathArg(fs, "out.csv")
+	check(os.MkdirAll(filepath.Dir(file), 0755))
+	filter := booking.BookingFilter{Property: *property, From: parseDateFlag(*from), To: parseDateFlag(*to)}
+	check(booking.ExportCSV(file, store, filter))
+}
+
+// runReport prints an owner statement for every property
+func runReport(args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	tenant := addTenantFlags(fs)
+	settingsFile := fs.String("settings", "settings.json", "settings file")
+	storeFile := fs.String("store", "bookings.jsonl", "booking store file")
+	from := fs.String("from", "", "first arrival date to report on")
+	to := fs.String("to", "", "report on arrivals before this date")
+	omitPII := fs.Bool("omit-pii", false, "leave guests' names out of the reports")
+	fs.Parse(args)
+	settings := tenant.settings(*settingsFile)
+	store := tenant.store(*storeFile)
+	reports, err := booking.BuildOwnerReports(store, settings, parseDateFlag(*from), parseDateFlag(*to))
+	check(err)
+	for _, r := range reports {
+		r.OmitPII = *omitPII
+		check(booking.WriteOwnerReport(os.Stdout, r))
+	}
+}
+
+// runTaxReturn writes the quarterly tax return for the stored bookings
+func runTaxReturn(args []string) {
+	fs := flag.NewFlagSet("taxreturn", flag.ExitOnError)
+	tenant := addTenantFlags(fs)
+	settingsFile := fs.String("settings", "settings.json", "settings file")
+	storeFile := fs.String("store", "bookings.jsonl", "booking store file")
+	year := fs.Int("year", 0, "year of the return (default this year)")
+	quarter := fs.Int("quarter", 0, "quarter of the return, 1 to 4 (default this quarter)")
+	fs.Parse(args)
+	*year, *quarter = taxPeriod(*year, *quarter, today(tenant.settings(*settingsFile)))
+	store := tenant.store(*storeFile)
+	r, err := booking.BuildTaxReturn(store, *year, *quarter)
+	check(err)
+	check(booking.WriteTaxReturn(os.Stdout, r))
+}
+
+// taxPeriod returns the year and quarter of a tax return, the current ones,
+// those on day, unless they are given
+func taxPeriod(year, quarter int, day time.Time) (int, int) {
+	if year == 0 {
+		year = day.Year()
+	}
+	if quarter == 0 {
+		quarter = (int(day.Month()) + 2) / 3
+	}
+	return year, quarter
+}
+
+// runGuests writes the history of every guest, or of the one with -id
+func runGuests(args []string) {
+	fs := flag.NewFlagSet("guests", flag.ExitOnError)
+	tenant := addTenantFlags(fs)
+	storeFile := fs.String("store", "bookings.jsonl", "booking store file")
+	id := fs.String("id", "", "only write the bookings of the guest with this ID")
+	fs.Parse(args)
+	store := tenant.store(*storeFile)
+	if *id != "" {
+		h, err := booking.GuestHistoryFor(store, *id)
+		check(err)
+		check(booking.WriteGuestHistory(os.Stdout, h))
+		return
+	}
+	histories, err := booking.GuestHistories(store)
+	check(err)
+	check(booking.WriteGuestHistories(os.Stdout, histories))
+}
+
+// runRedact anonymises the guests who departed before the retention period,
+// in the store, or in a bookings CSV if one is given
+func runRedact(args []string) {
+	fs := flag.NewFlagSet("redact", flag.ExitOnError)
+	tenant := addTenantFlags(fs)
+	settingsFile := fs.String("settings", "settings.json", "settings file")
+	storeFile := fs.String("store", "bookings.jsonl", "booking store file")
+	fs.String("out", "redacted.csv", "file to write the redacted CSV to")
+	fs.Parse(args)
+	settings := tenant.settings(*settingsFile)
+	if fs.NArg() > 0 {
+		in, err := os.Open(fs.Arg(0))
+		check(err)
+		defer in.Close()
+		out := tenant.pathFlag(fs, "out")
+		w := createFile(out)
+		n, err := booking.RedactCSV(in, w, settings, clock())
+		check(err)
+		check(w.Close())
+		log.Printf("redacted %d bookings from %s into %s", n, fs.Arg(0), out)
+		return
+	}
+	store := tenant.store(*storeFile)
+	n, err := booking.RedactStore(store, settings, clock())
+	check(err)
+	log.Printf("redacted %d bookings", n)
+}
+
+// runSubjectAccess writes everything stored about the guest with an email
+// address as JSON
+func runSubjectAccess(args []string) {
+	fs := flag.NewFlagSet("sar", flag.ExitOnError)
+	tenant := addTenantFlags(fs)
+	storeFile := fs.String("store", "bookings.jsonl", "booking store file")
+	email := fs.String("email", "", "the guest's email address")
+	fs.Parse(args)
+	store := tenant.store(*storeFile)
+	histories, err := booking.SubjectAccess(store, *email)
+	check(err)
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	check(enc.Encode(histories))
+}
+
+// runMail writes the emails due to guests today, or on -date, to .eml files,
+// or one kind of email about the booking with -ref
+func runMail(args []string) {
+	fs := flag.NewFlagSet("mail", flag.ExitOnError)
+	tenant := addTenantFlags(fs)
+	settingsFile := fs.String("settings", "settings.json", "settings file")
+	storeFile := fs.String("store", "bookings.jsonl", "booking store file")
+	dir := fs.String("dir", "outbox", "directory to write .eml files to")
+	date := fs.String("date", "", "day to send the emails due on (default today)")
+	ref := fs.String("ref", "", "only email the guest of this booking")
+	kind := fs.String("kind", booking.ConfirmationEmail, "kind of email to send with -ref: confirmation, pre_arrival or thank_you")
+	fs.Parse(args)
+	settings := tenant.settings(*settingsFile)
+	store := tenant.store(*storeFile)
+	outbox := tenant.dir(*dir)
+	mailer := booking.EMLMailer{Dir: outbox}
+	if *ref != "" {
+		b, err := store.Get(*ref)
+		check(err)
+		m, err := booking.RenderEmail(b, *kind, settings.MailFrom)
+		check(err)
+		check(mailer.Send(m))
+		return
+	}
+	day := today(settings)
+	if *date != "" {
+		day = parseDateFlag(*date)
+	}
+	n, err := booking.SendDueEmails(store, settings, mailer, day)
+	check(err)
+	log.Printf("wrote %d emails to %s", n, outbox)
+}
+
+// runJobs writes the greeting, cleaning and laundry jobs for the stored
+// bookings as CSV or iCalendar
+func runJobs(args []string) {
+	fs := flag.NewFlagSet("jobs", flag.ExitOnError)
+	tenant := addTenantFlags(fs)
+	settingsFile := fs.String("settings", "settings.json", "settings file")
+	storeFile := fs.String("store", "bookings.jsonl", "booking store file")
+	from := fs.String("from", "", "first day to plan (default today)")
+	to := fs.String("to", "", "plan up to this day (default a week after -from)")
+	format := fs.String("format", "csv", "csv or ics")
+	fs.Parse(args)
+	store := tenant.store(*storeFile)
+	start := today(tenant.settings(*settingsFile))
+	if *from != "" {
+		start = parseDateFlag(*from)
+	}
+	end := start.AddDate(0, 0, 7)
+	if *to != "" {
+		end = parseDateFlag(*to)
+	}
+	bookings, err := store.List(booking.BookingFilter{To: end})
+	check(err)
+	days := booking.PlanJobs(bookings, start, end)
+	switch *format {
+	case "csv":
+		check(booking.WriteJobsCSV(os.Stdout, days))
+	case "ics":
+		check(booking.WriteJobsICS(os.Stdout, days))
+	default:
+		log.Fatalf("unknown format %q", *format)
+	}
+}
+
+// runGenerate writes a synthetic bookings CSV, for trying out the other commands
+func runGenerate(args []string) {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	tenant := addTenantFlags(fs)
+	settingsFile := fs.String("settings", "settings.json", "settings file")
+	seed := fs.Int64("seed", 1, "random seed; the same seed generates the same bookings")
+	properties := fs.String("properties", "", "comma separated short names of the properties to book (default all)")
+	from := fs.String("from", "", "first arrival date (default the start of this year)")
+	to := fs.String("to", "", "last departure date (default a year after -from)")
+	demand := fs.String("demand", "", "12 comma separated weights of how busy each month is, January first (default seasonal)")
+	sources := fs.String("sources", "", "comma separated source=weight pairs, e.g. booking.com=35,airbnb=30 (default a typical mix)")
+	fs.String("out", "bookings.csv", "file to write the bookings to")
+	fs.Parse(args)
+	scenario := generators.Scenario{Seed: *seed, Settings: tenant.settings(*settingsFile)}
+	if *properties != "" {
+		scenario.Properties = strings.Split(*properties, ",")
+	}
+	var err error
+	if *demand != "" {
+		scenario.Demand, err = generators.ParseDemand(*demand)
+		check(err)
+	}
+	if *sources != "" {
+		scenario.SourceMix, err = generators.ParseSourceMix(*sources)
+		check(err)
+	}
+	check(scenario.Validate())
+	scenario.From = booking.Datetime(today(scenario.Settings).Year(), time.January, 1)
+	if *from != "" {
+		scenario.From = parseDateFlag(*from)
+	}
+	scenario.To = scenario.From.AddDate(1, 0, 0)
+	if *to != "" {
+		scenario.To = parseDateFlag(*to)
+	}
+	out := tenant.pathFlag(fs, "out")
+	w := createFile(out)
+	check(scenario.WriteCSV(w))
+	check(w.Close())
+	log.Printf("wrote synthetic bookings to %s", out)
+}
+
+// runStatus changes the status of a stored booking
+func runStatus(args []string) {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	tenant := addTenantFlags(fs)
+	settingsFile := fs.String("settings", "settings.json", "settings file")
+	storeFile := fs.String("store", "bookings.jsonl", "booking store file")
+	ref := fs.String("ref", "", "booking reference")
+	statusName := fs.String("status", "", "confirmed, enquiry, cancelled, no-show or completed")
+	date := fs.String("date", "", "date of the change, e.g. when the guest cancelled (default today)")
+	fs.Parse(args)
+	settings := tenant.settings(*settingsFile)
+	store := tenant.store(*storeFile)
+	status, ok := booking.ParseStatus(*statusName)
+	if !ok {
+		log.Fatalf("unknown status %q", *statusName)
+	}
+	on := today(settings)
+	if *date != "" {
+		on = parseDateFlag(*date)
+	}
+	b, err := booking.SetBookingStatus(store, settings, *ref, status, on)
 	check(err)
-	settings := GetSettings(jsonByteArray)
-	spreadsheet := FixCSV("bookings.csv", settings)
-	WriteFixedCSV(spreadsheet)
+	log.Printf("%s is %s, refund %.2f", b.Form.BookingRef, b.Form.Status, b.Refund)
+}
+
+// runAmend moves a stored booking to new dates, giving it a new reference
+func runAmend(args []string) {
+	fs := flag.NewFlagSet("amend", flag.ExitOnError)
+	tenant := addTenantFlags(fs)
+	settingsFile := fs.String("settings", "settings.json", "settings file")
+	storeFile := fs.String("store", "bookings.jsonl", "booking store file")
+	ref := fs.String("ref", "", "booking reference")
+	arrival := fs.String("arrival", "", "new arrival date")
+	departure := fs.String("departure", "", "new departure date")
+	fs.Parse(args)
+	settings := tenant.settings(*settingsFile)
+	store := tenant.store(*storeFile)
+	b, err := booking.AmendBooking(store, settings, *ref, parseDateFlag(*arrival), parseDateFlag(*departure), today(settings))
+	check(err)
+	log.Printf("%s is now %s", *ref, b.Form.BookingRef)
+}
+
+// dateFlagOrToday returns the date in value, or today in the business's
+// timezone if there is none
+func dateFlagOrToday(value string, settings booking.Settings) time.Time {
+	if value == "" {
+		return today(settings)
+	}
+	return parseDateFlag(value)
+}
+
+// runAccrue credits house owners in the ledger with the owner income of the
+// bookings that have departed
+func runAccrue(args []string) {
+	fs := flag.NewFlagSet("accrue", flag.ExitOnError)
+	tenant := addTenantFlags(fs)
+	settingsFile := fs.String("settings", "settings.json", "settings file")
+	storeFile := fs.String("store", "bookings.jsonl", "booking store file")
+	ledgerFile := fs.String("ledger", "ledger.jsonl", "house owner ledger file")
+	date := fs.String("date", "", "accrue bookings departed on or before this date (default today)")
+	fs.Parse(args)
+	settings := tenant.settings(*settingsFile)
+	n, err := booking.AccrueOwnerIncome(tenant.store(*storeFile), settings, tenant.ledger(*ledgerFile),
+		dateFlagOrToday(*date, settings))
+	check(err)
+	log.Printf("posted %d accruals", n)
+}
+
+// runAdjust credits a house owner in the ledger, or debits them
+func runAdjust(args []string) {
+	fs := flag.NewFlagSet("adjust", flag.ExitOnError)
+	tenant := addTenantFlags(fs)
+	settingsFile := fs.String("settings", "settings.json", "settings file")
+	ledgerFile := fs.String("ledger", "ledger.jsonl", "house owner ledger file")
+	id := fs.String("owner", "", "id of the house owner")
+	amount := fs.Float64("amount", 0, "amount to credit the owner with, negative to debit them")
+	memo := fs.String("memo", "", "what the adjustment is for")
+	date := fs.String("date", "", "date of the adjustment (default today)")
+	fs.Parse(args)
+	settings := tenant.settings(*settingsFile)
+	owner, err := settings.Owner(*id)
+	if err == booking.ErrOwnerNotFound {
+		log.Fatalf("no house owner %q", *id)
+	}
+	check(booking.PostAdjustment(tenant.ledger(*ledgerFile), owner, *amount, *memo, dateFlagOrToday(*date, settings)))
+}
+
+// runBalances prints what every house owner is owed
+func runBalances(args []string) {
+	fs := flag.NewFlagSet("balances", flag.ExitOnError)
+	tenant := addTenantFlags(fs)
+	settingsFile := fs.String("settings", "settings.json", "settings file")
+	ledgerFile := fs.String("ledger", "ledger.jsonl", "house owner ledger file")
+	date := fs.String("date", "", "balances on this date (default today)")
+	fs.Parse(args)
+	settings := tenant.settings(*settingsFile)
+	day := dateFlagOrToday(*date, settings)
+	balances, err := booking.OwnerBalances(tenant.ledger(*ledgerFile), settings, day)
+	check(err)
+	check(booking.WriteOwnerBalances(os.Stdout, balances, day))
+}
+
+// runPayouts writes the payouts due to house owners to a CSV for the bank,
+// and with -record, posts them to the ledger as made
+func runPayouts(args []string) {
+	fs := flag.NewFlagSet("payouts", flag.ExitOnError)
+	tenant := addTenantFlags(fs)
+	settingsFile := fs.String("settings", "settings.json", "settings file")
+	ledgerFile := fs.String("ledger", "ledger.jsonl", "house owner ledger file")
+	date := fs.String("date", "", "date of the payouts (default today)")
+	fs.String("out", "payouts.csv", "payout file")
+	record := fs.Bool("record", false, "post the payouts to the ledger")
+	fs.Parse(args)
+	settings := tenant.settings(*settingsFile)
+	ledger := tenant.ledger(*ledgerFile)
+	payouts, err := booking.PlanPayouts(ledger, settings, dateFlagOrToday(*date, settings))
+	check(err)
+	out := tenant.pathFlag(fs, "out")
+	file := createFile(out)
+	check(booking.WritePayoutsCSV(file, payouts))
+	check(file.Close())
+	if *record {
+		check(booking.RecordPayouts(ledger, payouts))
+	}
+	log.Printf("wrote %d payouts to %s", len(payouts), out)
+}
+
+// runReconcile matches the money coming in on a bank statement, CSV or OFX,
+// to the stored bookings, marking them paid, and prints what it found
+func runReconcile(args []string) {
+	fs := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	tenant := addTenantFlags(fs)
+	storeFile := fs.String("store", "bookings.jsonl", "booking store file")
+	window := fs.Int("window", booking.DefaultReconcileWindow,
+		"days either side of the due date to match payments without a booking reference")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		log.Fatal("reconcile needs a bank statement, e.g. statement.csv or statement.ofx")
+	}
+	store := tenant.store(*storeFile)
+	var transactions []booking.Transaction
+	for _, file := range fs.Args() {
+		t, err := booking.ReadStatement(file)
+		check(err)
+		transactions = append(transactions, t...)
+	}
+	r, err := booking.Reconcile(store, transactions, *window)
+	check(err)
+	check(booking.WriteReconciliation(os.Stdout, r))
+}
+
+func main() {
+	cmd, args := "fix", os.Args[1:]
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+	switch cmd {
+	case "fix":
+		runFix(args)
+	case "serve":
+		runServe(args)
+	case "import":
+		runImport(args)
+	case "export":
+		runExport(args)
+	case "report":
+		runReport(args)
+	case "taxreturn":
+		runTaxReturn(args)
+	case "guests":
+		runGuests(args)
+	case "redact":
+		runRedact(args)
+	case "sar":
+		runSubjectAccess(args)
+	case "mail":
+		runMail(args)
+	case "jobs":
+		runJobs(args)
+	case "generate":
+		runGenerate(args)
+	case "status":
+		runStatus(args)
+	case "amend":
+		runAmend(args)
+	case "accrue":
+		runAccrue(args)
+	case "adjust":
+		runAdjust(args)
+	case "balances":
+		runBalances(args)
+	case "payouts":
+		runPayouts(args)
+	case "reconcile":
+		runReconcile(args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", cmd)
+		os.Exit(2)
+	}
 }
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tintinnabulate/supreme-garbanzo/booking"
+)
+
+func Test_tenantFlags_path(t *testing.T) {
+	dir := t.TempDir()
+	tenants := filepath.Join(dir, "tenants.json")
+	config := `{"tenants": [{"id": "bath", "settings": "bath.json", "store": "bath.jsonl"},
+		{"id": "lakes", "settings": "lakes.json", "store": "lakes.jsonl"}]}`
+	if err := ioutil.WriteFile(tenants, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	tenant := addTenantFlags(fs)
+	fs.String("out", "payouts.csv", "")
+	fs.String("in", "in.csv", "")
+	fs.Parse([]string{"-tenants", tenants, "-tenant", "lakes", "-in", "mine.csv"})
+	if got := tenant.pathFlag(fs, "out"); got != filepath.Join("lakes", "payouts.csv") {
+		t.Errorf("pathFlag(out) = %v, want it in the tenant's directory", got)
+	}
+	if got := tenant.pathFlag(fs, "in"); got != "mine.csv" {
+		t.Errorf("pathFlag(in) = %v, want it as given", got)
+	}
+	if got := tenant.path("outbox/x.eml"); got != filepath.Join("outbox", "lakes", "x.eml") {
+		t.Errorf("path() = %v", got)
+	}
+	// the tenants file is read once
+	os.Remove(tenants)
+	if c, ok := tenant.config(); !ok || c.ID != "lakes" {
+		t.Errorf("config() = %v, %v", c, ok)
+	}
+
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	tenant = addTenantFlags(fs)
+	fs.Parse(nil)
+	if got := tenant.path("out.csv"); got != "out.csv" {
+		t.Errorf("path() without tenants = %v, want out.csv", got)
+	}
+}
+
+func Test_taxPeriod(t *testing.T) {
+	// already the third quarter in London, but not yet by the machine's UTC clock
+	defer func(c booking.Clock) { clock = c }(clock)
+	clock = func() time.Time { return time.Date(2017, time.June, 30, 23, 30, 0, 0, time.UTC) }
+	day := today(booking.Settings{Timezone: "Europe/London"})
+	if year, quarter := taxPeriod(0, 0, day); year != 2017 || quarter != 3 {
+		t.Errorf("taxPeriod() = %v Q%v, want 2017 Q3", year, quarter)
+	}
+	if year, quarter := taxPeriod(2016, 4, day); year != 2016 || quarter != 4 {
+		t.Errorf("taxPeriod() = %v Q%v, want the period given, 2016 Q4", year, quarter)
+	}
+}
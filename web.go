@@ -0,0 +1,676 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tintinnabulate/supreme-garbanzo/booking"
+)
+
+var formTemplate = template.Must(template.New("form").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>New booking</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 2em auto; }
+label { display: block; margin-top: 0.8em; }
+.error { color: #b00; font-size: 0.9em; }
+.saved { background: #dfd; padding: 0.5em; }
+#preview td { padding: 0 1em 0 0; }
+</style>
+</head>
+<body>
+<h1>New booking</h1>
+{{if .Saved}}<p class="saved">Booking saved with reference <strong>{{.Saved.Form.BookingRef}}</strong></p>{{end}}
+<form id="booking" method="post" action="bookings">
+<label>Property
+<select name="property">
+{{range .Properties}}<option value="{{.ShortName}}"{{if eq .ShortName ($.Value "property")}} selected{{end}}>{{.LongName}}</option>
+{{end}}</select></label>
+{{with .Errors.property}}<div class="error">{{.}}</div>{{end}}
+<label>Arrival <input type="date" name="arrival" value="{{.Value "arrival"}}"></label>
+{{with .Errors.arrival}}<div class="error">{{.}}</div>{{end}}
+<label>Departure <input type="date" name="departure" value="{{.Value "departure"}}"></label>
+{{with .Errors.departure}}<div class="error">{{.}}</div>{{end}}
+<label>Booking date <input type="date" name="booking_date" value="{{.Value "booking_date"}}"></label>
+{{with .Errors.booking_date}}<div class="error">{{.}}</div>{{end}}
+<label>First name <input name="first_name" value="{{.Value "first_name"}}"></label>
+{{with .Errors.first_name}}<div class="error">{{.}}</div>{{end}}
+<label>Last name <input name="last_name" value="{{.Value "last_name"}}"></label>
+{{with .Errors.last_name}}<div class="error">{{.}}</div>{{end}}
+<label>Email <input type="email" name="email" value="{{.Value "email"}}"></label>
+{{with .Errors.email}}<div class="error">{{.}}</div>{{end}}
+<label>Mobile <input type="tel" name="mobile" value="{{.Value "mobile"}}"></label>
+{{with .Errors.mobile}}<div class="error">{{.}}</div>{{end}}
+<label>Source
+<select name="source">
+{{range .Sources}}<option value="{{.}}"{{if eq .String ($.Value "source")}} selected{{end}}>{{.}}</option>
+{{end}}</select></label>
+{{with .Errors.source}}<div class="error">{{.}}</div>{{end}}
+<label><input type="checkbox" name="owner_direct"{{if .Checked "owner_direct"}} checked{{end}}> Taken directly by the house owner</label>
+<label>Number of people <input type="number" min="1" name="number_of_people" value="{{.Value "number_of_people"}}"></label>
+{{with .Errors.number_of_people}}<div class="error">{{.}}</div>{{end}}
+<label>Of whom children <input type="number" min="0" name="number_of_children" value="{{.Value "number_of_children"}}"></label>
+{{with .Errors.number_of_children}}<div class="error">{{.}}</div>{{end}}
+<label>Gross <input type="number" step="0.01" min="0" name="gross" value="{{.Value "gross"}}"></label>
+{{with .Errors.gross}}<div class="error">{{.}}</div>{{end}}
+<fieldset>
+<legend>Discount</legend>
+<label>Kind
+<select name="discount_kind">
+{{range .DiscountKinds}}<option value="{{.}}"{{if eq .String ($.Value "discount_kind")}} selected{{end}}>{{.}}</option>
+{{end}}</select></label>
+{{with .Errors.discount_kind}}<div class="error">{{.}}</div>{{end}}
+<label>Amount (percent, or a sum of money) <input type="number" step="0.01" min="0" name="discount_amount" value="{{.Value "discount_amount"}}"></label>
+{{with .Errors.discount_amount}}<div class="error">{{.}}</div>{{end}}
+<label>Reason <input name="discount_reason" value="{{.Value "discount_reason"}}"></label>
+<label>Approved by <input name="discount_approved_by" value="{{.Value "discount_approved_by"}}"></label>
+{{with .Errors.discount_approved_by}}<div class="error">{{.}}</div>{{end}}
+</fieldset>
+<fieldset>
+<legend>Services</legend>
+<label><input type="checkbox" name="greeting"{{if .Checked "greeting"}} checked{{end}}> Greeting</label>
+<label><input type="checkbox" name="laundry"{{if .Checked "laundry"}} checked{{end}}> Laundry</label>
+<label><input type="checkbox" name="cleaning"{{if .Checked "cleaning"}} checked{{end}}> Cleaning</label>
+<label><input type="checkbox" name="consumables"{{if .Checked "consumables"}} checked{{end}}> Consumables</label>
+{{range .ExtraItems}}<label>Number of {{.}} <input type="number" min="0" name="extra_{{.}}" value="{{$.Value (print "extra_" .)}}"></label>
+{{with index $.Errors (print "extra_" .)}}<div class="error">{{.}}</div>{{end}}
+{{end}}</fieldset>
+<label>Notes <textarea name="notes">{{.Value "notes"}}</textarea></label>
+<h2>Fees</h2>
+<table id="preview">
+<tr><td>Booking reference</td><td id="booking_ref"></td></tr>
+<tr><td>Discount</td><td id="discount"></td></tr>
+<tr><td>Booking fee</td><td id="booking_fee"></td></tr>
+<tr><td>Net</td><td id="net"></td></tr>
+<tr><td>Payment due</td><td id="due_date"></td></tr>
+<tr><td>Agency commission</td><td id="agency_commission"></td></tr>
+<tr><td>House owner fee</td><td id="house_owner_fee"></td></tr>
+<tr><td>Total fees</td><td id="total_fees"></td></tr>
+<tr><td>Owner income</td><td id="owner_income"></td></tr>
+<tr><td>Tourist tax</td><td id="tourist_tax"></td></tr>
+<tr><td>VAT</td><td id="vat"></td></tr>
+</table>
+<p><button type="submit">Save booking</button></p>
+</form>
+<script>
+var form = document.getElementById("booking");
+function preview() {
+	fetch("preview", {method: "POST", body: new URLSearchParams(new FormData(form))})
+		.then(function(r) { return r.json(); })
+		.then(function(p) {
+			for (var k in p) {
+				var el = document.getElementById(k);
+				if (el) { el.textContent = p[k]; }
+			}
+		});
+}
+form.addEventListener("input", preview);
+form.addEventListener("change", preview);
+preview();
+</script>
+</body>
+</html>
+`))
+
+// bookingFormPage is what the booking entry form template is rendered from
+type bookingFormPage struct {
+	Properties    []booking.Property
+	Sources       []booking.Source
+	DiscountKinds []booking.DiscountKind
+	ExtraItems    []string
+	Values        url.Values
+	Errors        map[string]string
+	Saved         *booking.Booking
+}
+
+// Value returns the value the user entered for the named field
+func (p bookingFormPage) Value(field string) string {
+	return p.Values.Get(field)
+}
+
+// Checked returns whether the named checkbox was ticked
+func (p bookingFormPage) Checked(field string) bool {
+	return p.Values.Get(field) != ""
+}
+
+type server struct {
+	settings booking.Settings
+	store    booking.BookingStore
+	mux      *http.ServeMux
+	// now is time.Now, but for tests
+	now booking.Clock
+}
+
+func newServer(settings booking.Settings, store booking.BookingStore) *server {
+	s := &server{settings: settings, store: store, mux: http.NewServeMux(), now: time.Now}
+	s.mux.HandleFunc("/", s.handleForm)
+	s.mux.HandleFunc("/bookings", s.handleCreate)
+	s.mux.HandleFunc("/preview", s.handlePreview)
+	s.mux.HandleFunc("/api/bookings", s.handleAPIBookings)
+	s.mux.HandleFunc("/api/bookings/", s.handleAPIBooking)
+	s.mux.HandleFunc("/api/guests", s.handleAPIGuests)
+	s.mux.HandleFunc("/api/guests/", s.handleAPIGuest)
+	return s
+}
+
+// today is the business's date now
+func (s *server) today() time.Time {
+	return s.settings.Today(s.now())
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// tenantSummary is what /api/tenants lists of each tenant
+type tenantSummary struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// newTenantsServer serves each tenant's form and API under /<tenant id>/,
+// e.g. /bath/api/bookings, and lists the tenants at /api/tenants. Each
+// tenant is served by its own server, which only has its own settings and
+// store, so a tenant never sees another's bookings.
+func newTenantsServer(tenants []booking.Tenant) http.Handler {
+	mux := http.NewServeMux()
+	var summaries []tenantSummary
+	for _, t := range tenants {
+		prefix := "/" + t.ID
+		mux.Handle(prefix+"/", http.StripPrefix(prefix, newServer(t.Settings, t.Store)))
+		summaries = append(summaries, tenantSummary{ID: t.ID, Name: t.Name})
+	}
+	mux.HandleFunc("/api/tenants", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, summaries)
+	})
+	return mux
+}
+
+func (s *server) render(w http.ResponseWriter, status int, values url.Values, errs map[string]string, saved *booking.Booking) {
+	var sources []booking.Source
+	for x := booking.BookingCom; x <= booking.Other; x++ {
+		sources = append(sources, x)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	err := formTemplate.Execute(w, bookingFormPage{
+		Properties:    s.settings.Properties,
+		Sources:       sources,
+		DiscountKinds: []booking.DiscountKind{booking.NoDiscount, booking.PercentDiscount, booking.FixedDiscount},
+		ExtraItems:    s.settings.ExtraItems(),
+		Values:        values,
+		Errors:        errs,
+		Saved:         saved,
+	})
+	if err != nil {
+		log.Println("rendering booking form:", err)
+	}
+}
+
+// defaultFormValues are what a blank booking form is filled in with, booked today
+func defaultFormValues(today time.Time) url.Values {
+	return url.Values{
+		"booking_date":     {today.Format(booking.DateLayout)},
+		"number_of_people": {"1"},
+		"greeting":         {"on"},
+		"laundry":          {"on"},
+		"cleaning":         {"on"},
+		"consumables":      {"on"},
+	}
+}
+
+func (s *server) handleForm(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	s.render(w, http.StatusOK, defaultFormValues(s.today()), nil, nil)
+}
+
+func (s *server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseForm()
+	f, errs := parseBookingForm(r.PostForm, s.settings)
+	if len(errs) > 0 {
+		s.render(w, http.StatusBadRequest, r.PostForm, errs, nil)
+		return
+	}
+	b := booking.CreateBooking(f, s.settings)
+	if err := booking.LinkGuest(s.store, &b); err != nil {
+		log.Println("linking guest:", err)
+		http.Error(w, "could not save the booking", http.StatusInternalServerError)
+		return
+	}
+	if err := s.store.Create(b); err != nil {
+		if err == booking.ErrBookingExists {
+			errs["departure"] = "a booking with reference " + f.BookingRef + " already exists"
+			s.render(w, http.StatusConflict, r.PostForm, errs, nil)
+			return
+		}
+		log.Println("saving booking:", err)
+		http.Error(w, "could not save the booking", http.StatusInternalServerError)
+		return
+	}
+	s.render(w, http.StatusOK, defaultFormValues(s.today()), nil, &b)
+}
+
+func (s *server) handlePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseForm()
+	preview := make(map[string]string)
+	f, errs := parseBookingForm(r.PostForm, s.settings)
+	if len(errs) == 0 {
+		b := booking.CreateBooking(f, s.settings)
+		preview["booking_ref"] = f.BookingRef
+		preview["discount"] = strconv.FormatFloat(b.DiscountAmount, 'f', 2, 64)
+		preview["booking_fee"] = strconv.FormatFloat(b.BookingFee, 'f', 2, 64)
+		preview["net"] = strconv.FormatFloat(b.Net, 'f', 2, 64)
+		preview["due_date"] = b.DueDate.Format(booking.DateLayout)
+		preview["agency_commission"] = strconv.FormatFloat(b.AgencyCommission, 'f', 2, 64)
+		preview["house_owner_fee"] = strconv.FormatFloat(b.HouseOwnerFee, 'f', 2, 64)
+		preview["total_fees"] = strconv.FormatFloat(b.TotalFees, 'f', 2, 64)
+		preview["owner_income"] = strconv.FormatFloat(b.OwnerIncome, 'f', 2, 64)
+		preview["tourist_tax"] = strconv.FormatFloat(booking.TaxTotal(b.Taxes, false), 'f', 2, 64)
+		preview["vat"] = strconv.FormatFloat(booking.TaxTotal(b.Taxes, true), 'f', 2, 64)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
+
+func parseFormDate(values url.Values, field string, errs map[string]string) time.Time {
+	date, err := booking.ParseDate(values.Get(field))
+	if err != nil {
+		errs[field] = "enter a date as YYYY-MM-DD"
+	}
+	return date
+}
+
+// parseDiscountForm validates the discount part of a booking form. Percentages
+// are entered as e.g. 10 for 10% off.
+func parseDiscountForm(values url.Values, gross float64, errs map[string]string) booking.Discount {
+	var d booking.Discount
+	var ok bool
+	if d.Kind, ok = booking.ParseDiscountKind(values.Get("discount_kind")); !ok && values.Get("discount_kind") != "" {
+		errs["discount_kind"] = "choose a kind of discount"
+	}
+	if d.Kind == booking.NoDiscount {
+		return booking.Discount{}
+	}
+	amount, err := strconv.ParseFloat(values.Get("discount_amount"), 64)
+	switch {
+	case err != nil || amount <= 0:
+		errs["discount_amount"] = "enter how much discount was given"
+	case d.Kind == booking.PercentDiscount && amount > 100:
+		errs["discount_amount"] = "a discount cannot be more than 100%"
+	case d.Kind == booking.FixedDiscount && amount > gross:
+		errs["discount_amount"] = "a discount cannot be more than the gross"
+	}
+	d.Amount = amount
+	if d.Kind == booking.PercentDiscount {
+		d.Amount = amount / 100
+	}
+	d.Reason = strings.TrimSpace(values.Get("discount_reason"))
+	d.ApprovedBy = strings.TrimSpace(values.Get("discount_approved_by"))
+	if d.ApprovedBy == "" {
+		errs["discount_approved_by"] = "enter who approved the discount"
+	}
+	return d
+}
+
+// parseBookingForm validates a submitted booking form and turns it into a
+// FormInput, generating the booking reference from the property and dates.
+// Any problems are returned keyed by form field.
+func parseBookingForm(values url.Values, settings booking.Settings) (booking.FormInput, map[string]string) {
+	errs := make(map[string]string)
+	var f booking.FormInput
+	var property booking.Property
+	found := false
+	for _, p := range settings.Properties {
+		if p.ShortName == values.Get("property") {
+			property, found = p, true
+		}
+	}
+	if !found {
+		errs["property"] = "choose a property"
+	}
+	arrival := parseFormDate(values, "arrival", errs)
+	departure := parseFormDate(values, "departure", errs)
+	if _, bad := errs["departure"]; !bad && !departure.After(arrival) {
+		errs["departure"] = "departure must be after arrival"
+	}
+	f.BookingDate = parseFormDate(values, "booking_date", errs)
+	f.FirstName = values.Get("first_name")
+	f.LastName = values.Get("last_name")
+	f.Email = values.Get("email")
+	f.Mobile = values.Get("mobile")
+	// a mobile number that cannot be normalised is kept as it was entered
+	property.NormaliseContact(&f)
+	if f.Email != "" && booking.CheckEmail(f.Email) != nil {
+		errs["email"] = "enter a valid email address"
+	}
+	if f.FirstName == "" {
+		errs["first_name"] = "enter the guest's first name"
+	}
+	if f.LastName == "" {
+		errs["last_name"] = "enter the guest's last name"
+	}
+	f.Notes = strings.TrimSpace(values.Get("notes"))
+	var ok bool
+	if f.Source, ok = booking.ParseSource(values.Get("source")); !ok {
+		errs["source"] = "choose where the booking came from"
+	}
+	var err error
+	if f.NumberOfPeople, err = strconv.Atoi(values.Get("number_of_people")); err != nil || f.NumberOfPeople < 1 {
+		errs["number_of_people"] = "enter at least 1 person"
+	} else if err = property.CheckPartySize(f.NumberOfPeople); found && err != nil {
+		errs["number_of_people"] = err.Error()
+	}
+	if children := values.Get("number_of_children"); children != "" {
+		if f.NumberOfChildren, err = strconv.Atoi(children); err != nil || f.NumberOfChildren < 0 || f.NumberOfChildren > f.NumberOfPeople {
+			errs["number_of_children"] = "enter no more children than people"
+		}
+	}
+	if f.Gross, err = strconv.ParseFloat(values.Get("gross"), 64); err != nil || f.Gross < 0 {
+		errs["gross"] = "enter the gross amount paid"
+	}
+	f.IsOwnerDirect = values.Get("owner_direct") != ""
+	f.Discount = parseDiscountForm(values, f.Gross, errs)
+	f.IsGreeting = values.Get("greeting") != ""
+	f.IsLaundry = values.Get("laundry") != ""
+	f.IsCleaning = values.Get("cleaning") != ""
+	f.IsConsumables = values.Get("consumables") != ""
+	for _, item := range settings.ExtraItems() {
+		field := "extra_" + item
+		if values.Get(field) == "" {
+			continue
+		}
+		n, err := strconv.Atoi(values.Get(field))
+		if err != nil || n < 0 {
+			errs[field] = "enter how many " + item
+			continue
+		}
+		if n > 0 {
+			if f.Extras == nil {
+				f.Extras = make(map[string]int)
+			}
+			f.Extras[item] = n
+		}
+	}
+	if len(errs) > 0 {
+		return f, errs
+	}
+	if f.BookingRef, err = booking.BookingRefFor(property, arrival, departure, f.BookingDate, settings); err != nil {
+		errs["departure"] = err.Error()
+	}
+	return f, errs
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch err {
+	case booking.ErrBookingNotFound, booking.ErrGuestNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case booking.ErrBookingExists:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Println("booking store:", err)
+		http.Error(w, "booking store failed", http.StatusInternalServerError)
+	}
+}
+
+// parseBookingFilter reads a BookingFilter from the query string, e.g.
+// ?property=FB&from=2017-01-01&to=2018-01-01&source=airbnb
+func parseBookingFilter(query url.Values) (booking.BookingFilter, error) {
+	filter := booking.BookingFilter{Property: query.Get("property")}
+	var err error
+	if v := query.Get("from"); v != "" {
+		if filter.From, err = booking.ParseDate(v); err != nil {
+			return filter, err
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if filter.To, err = booking.ParseDate(v); err != nil {
+			return filter, err
+		}
+	}
+	if v := query.Get("source"); v != "" {
+		var ok bool
+		if filter.Source, ok = booking.ParseSource(v); !ok {
+			return filter, fmt.Errorf("unknown source %q", v)
+		}
+	}
+	return filter, nil
+}
+
+// handleAPIBookings lists bookings with GET and creates one from a JSON
+// FormInput with POST
+func (s *server) handleAPIBookings(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		filter, err := parseBookingFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		bookings, err := s.store.List(filter)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, bookings)
+	case http.MethodPost:
+		var f booking.FormInput
+		if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, _, _, err := booking.ParseBookingRef(f.BookingRef, s.settings); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		b := booking.CreateBooking(f, s.settings)
+		if err := b.Property.CheckPartySize(f.NumberOfPeople); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		b.Property.NormaliseContact(&b.Form)
+		if err := booking.LinkGuest(s.store, &b); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		if err := s.store.Create(b); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, b)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAPIBooking gets, replaces or deletes the booking at /api/bookings/{ref}.
+// POSTs to /api/bookings/{ref}/status and /api/bookings/{ref}/amend change
+// its status or dates.
+func (s *server) handleAPIBooking(w http.ResponseWriter, r *http.Request) {
+	ref := strings.TrimPrefix(r.URL.Path, "/api/bookings/")
+	if i := strings.Index(ref, "/"); i >= 0 {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		switch ref[i+1:] {
+		case "status":
+			s.handleAPIStatus(w, r, ref[:i])
+		case "amend":
+			s.handleAPIAmend(w, r, ref[:i])
+		default:
+			http.NotFound(w, r)
+		}
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		b, err := s.store.Get(ref)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, b)
+	case http.MethodPut:
+		var f booking.FormInput
+		if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, _, _, err := booking.ParseBookingRef(ref, s.settings); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		old, err := s.store.Get(ref)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		if f.GuestID == "" {
+			f.GuestID = old.Form.GuestID
+		}
+		old.Form = f
+		old.Form.BookingRef = ref
+		b := booking.Recalculate(old, s.settings)
+		if err := b.Property.CheckPartySize(f.NumberOfPeople); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		b.Property.NormaliseContact(&b.Form)
+		if err := s.store.Update(b); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, b)
+	case http.MethodDelete:
+		if err := s.store.Delete(ref); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAPIStatus changes the status of a booking, from a body such as
+// {"status": "cancelled", "date": "2017-06-01"}
+func (s *server) handleAPIStatus(w http.ResponseWriter, r *http.Request, ref string) {
+	var body struct {
+		Status string `json:"status"`
+		Date   string `json:"date"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	status, ok := booking.ParseStatus(body.Status)
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown status %q", body.Status), http.StatusBadRequest)
+		return
+	}
+	on := s.today()
+	if body.Date != "" {
+		var err error
+		if on, err = booking.ParseDate(body.Date); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	b, err := booking.SetBookingStatus(s.store, s.settings, ref, status, on)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, b)
+}
+
+// handleAPIAmend moves a booking to new dates, from a body such as
+// {"arrival": "2017-06-18", "departure": "2017-06-21"}. The amended booking
+// has a new reference.
+func (s *server) handleAPIAmend(w http.ResponseWriter, r *http.Request, ref string) {
+	var body struct {
+		Arrival   string `json:"arrival"`
+		Departure string `json:"departure"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	arrival, err := booking.ParseDate(body.Arrival)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	departure, err := booking.ParseDate(body.Departure)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	b, err := booking.AmendBooking(s.store, s.settings, ref, arrival, departure, s.today())
+	switch err {
+	case nil:
+		writeJSON(w, http.StatusOK, b)
+	case booking.ErrBookingNotFound, booking.ErrBookingExists:
+		writeStoreError(w, err)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// handleAPIGuests lists the history of every guest
+func (s *server) handleAPIGuests(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	histories, err := booking.GuestHistories(s.store)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, histories)
+}
+
+// handleAPIGuest gets the history of the guest at /api/guests/{id}
+func (s *server) handleAPIGuest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h, err := booking.GuestHistoryFor(s.store, strings.TrimPrefix(r.URL.Path, "/api/guests/"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, h)
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tintinnabulate/supreme-garbanzo/booking"
+)
+
+var testSettings = booking.Settings{Properties: []booking.Property{
+	{LongName: "FooBarBaz", ShortName: "FB", Commission: 0.1, HouseOwnerCommission: 0.1,
+		Greeting: 15, Laundry: []float64{10, 10, 15, 15, 25, 25}, Cleaning: 35,
+		Consumables: []float64{15, 15, 25, 25, 35, 35}},
+	{LongName: "WibbleWobbleWoo", ShortName: "WW", Commission: 0.2, BookingCommission: 0.1,
+		HouseOwnerCommission: 0.3, Greeting: 25, Laundry: []float64{15, 15, 20, 20, 35, 35},
+		Cleaning: 35, Consumables: []float64{15, 15, 25, 25, 35, 35}},
+}}
+
+func validFormValues() url.Values {
+	return url.Values{
+		"property":         {"WW"},
+		"arrival":          {"2017-06-17"},
+		"departure":        {"2017-06-19"},
+		"booking_date":     {"2017-05-20"},
+		"first_name":       {"Ada"},
+		"last_name":        {"Lovelace"},
+		"email":            {"ada@example.com"},
+		"source":           {"airbnb"},
+		"number_of_people": {"2"},
+		"gross":            {"400"},
+		"cleaning":         {"on"},
+	}
+}
+
+func Test_parseBookingForm(t *testing.T) {
+	tests := []struct {
+		name      string
+		change    url.Values
+		wantRef   string
+		wantError string
+	}{
+		{"valid", url.Values{}, "6WWJUN1719", ""},
+		{"unknown property", url.Values{"property": {"XX"}}, "", "property"},
+		{"departure before arrival", url.Values{"departure": {"2017-06-16"}}, "", "departure"},
+		{"bad date", url.Values{"arrival": {"17/06/2017"}}, "", "arrival"},
+		{"no people", url.Values{"number_of_people": {"0"}}, "", "number_of_people"},
+		{"unknown source", url.Values{"source": {"carrier pigeon"}}, "", "source"},
+		{"missing name", url.Values{"first_name": {" "}}, "", "first_name"},
+		{"discount", url.Values{"discount_kind": {"percent"}, "discount_amount": {"10"},
+			"discount_approved_by": {"Jo"}}, "6WWJUN1719", ""},
+		{"unapproved discount", url.Values{"discount_kind": {"fixed"}, "discount_amount": {"20"}}, "", "discount_approved_by"},
+		{"discount over gross", url.Values{"discount_kind": {"fixed"}, "discount_amount": {"500"},
+			"discount_approved_by": {"Jo"}}, "", "discount_amount"},
+		{"unencodable dates", url.Values{"arrival": {"2018-01-02"}, "departure": {"2018-01-05"}}, "", "departure"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := validFormValues()
+			for k, v := range tt.change {
+				values[k] = v
+			}
+			f, errs := parseBookingForm(values, testSettings)
+			if tt.wantError == "" {
+				if len(errs) > 0 {
+					t.Fatalf("parseBookingForm() errors = %v", errs)
+				}
+				if f.BookingRef != tt.wantRef {
+					t.Errorf("parseBookingForm() ref = %v, want %v", f.BookingRef, tt.wantRef)
+				}
+				return
+			}
+			if _, ok := errs[tt.wantError]; !ok {
+				t.Errorf("parseBookingForm() errors = %v, want error on %v", errs, tt.wantError)
+			}
+		})
+	}
+}
+
+func Test_server_handleCreate(t *testing.T) {
+	store := booking.NewMemoryStore()
+	s := newServer(testSettings, store)
+	req := httptest.NewRequest(http.MethodPost, "/bookings", strings.NewReader(validFormValues().Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %v, want %v", rec.Code, http.StatusOK)
+	}
+	if !strings.Contains(rec.Body.String(), "6WWJUN1719") {
+		t.Errorf("response does not show the booking reference")
+	}
+	if _, err := store.Get("6WWJUN1719"); err != nil {
+		t.Errorf("booking was not stored: %v", err)
+	}
+
+	values := validFormValues()
+	values.Set("gross", "lots")
+	req = httptest.NewRequest(http.MethodPost, "/bookings", strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %v, want %v", rec.Code, http.StatusBadRequest)
+	}
+	if !strings.Contains(rec.Body.String(), "enter the gross amount paid") {
+		t.Errorf("response does not show the validation error inline")
+	}
+}
+
+func Test_server_handleForm_today(t *testing.T) {
+	settings := testSettings
+	settings.Timezone = "Europe/London"
+	s := newServer(settings, booking.NewMemoryStore())
+	// already the next day in London
+	s.now = func() time.Time { return time.Date(2017, time.June, 17, 23, 30, 0, 0, time.UTC) }
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(rec.Body.String(), `value="2017-06-18"`) {
+		t.Errorf("form is not booked today, 2017-06-18: %s", rec.Body.String())
+	}
+}
+
+func Test_tenantsServer(t *testing.T) {
+	bath, lakes := booking.NewMemoryStore(), booking.NewMemoryStore()
+	h := newTenantsServer([]booking.Tenant{
+		{ID: "bath", Name: "Bath Holiday Lets", Settings: testSettings, Store: bath},
+		{ID: "lakes", Name: "Lakeland Cottages", Settings: testSettings, Store: lakes},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/bath/bookings", strings.NewReader(validFormValues().Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %v, want %v", rec.Code, http.StatusOK)
+	}
+	if _, err := bath.Get("6WWJUN1719"); err != nil {
+		t.Errorf("booking was not stored for bath: %v", err)
+	}
+	for path, want := range map[string]int{
+		"/bath/api/bookings/6WWJUN1719":  http.StatusOK,
+		"/lakes/api/bookings/6WWJUN1719": http.StatusNotFound,
+		"/api/bookings/6WWJUN1719":       http.StatusNotFound,
+		"/york/":                         http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s status = %v, want %v", path, rec.Code, want)
+		}
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tenants", nil))
+	if !strings.Contains(rec.Body.String(), `"id":"lakes","name":"Lakeland Cottages"`) {
+		t.Errorf("GET /api/tenants = %s", rec.Body.String())
+	}
+	// the form posts to the tenant it was served by
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/lakes/", nil))
+	if !strings.Contains(rec.Body.String(), `action="bookings"`) {
+		t.Errorf("form does not post relative to /lakes/")
+	}
+}
+
+func Test_server_api(t *testing.T) {
+	store := booking.NewMemoryStore()
+	s := newServer(testSettings, store)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+	form := `{"BookingRef": "6WWJUN1719", "FirstName": "Ada", "Source": 2, "NumberOfPeople": 2,
+		"Gross": 400, "BookingDate": "2017-05-20T00:00:00Z"}`
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"create", http.MethodPost, "/api/bookings", form, http.StatusCreated},
+		{"create again", http.MethodPost, "/api/bookings", form, http.StatusConflict},
+		{"create bad json", http.MethodPost, "/api/bookings", `{"BookingRef":`, http.StatusBadRequest},
+		{"create malformed ref", http.MethodPost, "/api/bookings",
+			`{"BookingRef": "6WWJUN17", "NumberOfPeople": 2, "Gross": 400}`, http.StatusBadRequest},
+		{"create unknown property", http.MethodPost, "/api/bookings",
+			`{"BookingRef": "6XXJUN1719", "NumberOfPeople": 2, "Gross": 400}`, http.StatusBadRequest},
+		{"update", http.MethodPut, "/api/bookings/6WWJUN1719",
+			`{"FirstName": "Ada", "Source": 2, "NumberOfPeople": 2, "Gross": 500}`, http.StatusOK},
+		{"update bad json", http.MethodPut, "/api/bookings/6WWJUN1719", `[`, http.StatusBadRequest},
+		{"update malformed ref", http.MethodPut, "/api/bookings/6WWJUN",
+			`{"NumberOfPeople": 2, "Gross": 500}`, http.StatusBadRequest},
+		{"update missing", http.MethodPut, "/api/bookings/6FBJUN1719",
+			`{"NumberOfPeople": 2, "Gross": 500}`, http.StatusNotFound},
+		{"delete", http.MethodDelete, "/api/bookings/6WWJUN1719", "", http.StatusNoContent},
+		{"delete again", http.MethodDelete, "/api/bookings/6WWJUN1719", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := do(tt.method, tt.path, tt.body)
+		if rec.Code != tt.wantStatus {
+			t.Fatalf("%s: %s %s status = %v, want %v: %s", tt.name, tt.method, tt.path, rec.Code, tt.wantStatus, rec.Body)
+		}
+		switch tt.name {
+		case "create":
+			if b, err := store.Get("6WWJUN1719"); err != nil || b.Form.FirstName != "Ada" {
+				t.Errorf("created booking = %+v, %v", b.Form, err)
+			}
+		case "update":
+			if b, _ := store.Get("6WWJUN1719"); b.Form.Gross != 500 || b.Arrival.Year() != 2017 {
+				t.Errorf("updated booking = %+v", b.Form)
+			}
+		}
+	}
+	if bookings, _ := store.List(booking.BookingFilter{}); len(bookings) != 0 {
+		t.Errorf("store has %d bookings after the delete, and no malformed ones", len(bookings))
+	}
+}